@@ -0,0 +1,141 @@
+package application
+
+import (
+	// native packages
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// apiKeyPrefix starts every API key, telling them apart from access tokens
+// (as both may be sent as bearer tokens).
+const apiKeyPrefix = "rrk_"
+
+// apiKeyPrefixLength is how much of an API key is stored (in the clear), so
+// that it may be recognised when listed.
+const apiKeyPrefixLength = len(apiKeyPrefix) + 6
+
+// The newAPIKey entity is used to unmarshall an API key to be issued. The
+// Username defaults to the admin issuing the key, and the key never expires
+// unless ExpiresAt is given.
+type newAPIKey struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Username  string     `json:"username"`
+	Scope     string     `json:"scope" validate:"oneof=read write"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// hashAPIKey returns the hash of an API key, which is stored in its place.
+// Keys are random, so (unlike passwords) a fast hash is enough.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// keyUser returns the user an API key acts as. Keys are only issued by
+// admins, so a key issued for the configured username acts as the configured
+// user (as the key issued by default to the configured user does).
+func (a *App) keyUser(username string) (recipes.User, error) {
+	if a.authUser != "" && username == a.authUser {
+		return a.configuredUser(), nil
+	}
+	return a.user(username)
+}
+
+// apiKeyUser returns the user which an API key was issued for. Read keys
+// only ever act as viewers.
+func (a *App) apiKeyUser(key string) (recipes.User, error) {
+	k := recipes.APIKey{KeyHash: hashAPIKey(key)}
+	if err := a.Store.UseAPIKey(&k); err != nil {
+		if err == recipes.ErrAPIKeyNotFound {
+			err = errUnauthenticated
+		}
+		return recipes.User{}, err
+	}
+	if k.ExpiresAt != nil && !time.Now().Before(*k.ExpiresAt) {
+		return recipes.User{}, errUnauthenticated
+	}
+	u, err := a.keyUser(k.Username)
+	if k.Scope == recipes.ReadScope {
+		u.Role = recipes.Viewer
+	}
+	return u, err
+}
+
+func (a *App) getAPIKeysEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	keys, err := a.Store.GetAPIKeys()
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, keys)
+}
+
+// createAPIKeyEndpoint issues an API key, which is only ever sent in this
+// response (as only its hash is stored).
+func (a *App) createAPIKeyEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var payload newAPIKey
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	if payload.Username == "" {
+		payload.Username = requestUser(req)
+	}
+	if _, err := a.keyUser(payload.Username); err != nil {
+		if err == errUnauthenticated {
+			respondWithValidationErrors(w, []FieldError{{Field: "username", Message: "is not a user"}})
+		} else {
+			respondWithStoreError(w, err)
+		}
+		return
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		respondWithValidationErrors(w, []FieldError{{Field: "expires_at", Message: "must be in the future"}})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	key := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	k := recipes.APIKey{
+		Name:      payload.Name,
+		Username:  payload.Username,
+		Scope:     payload.Scope,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashAPIKey(key),
+		ExpiresAt: payload.ExpiresAt,
+	}
+	if err := a.Store.CreateAPIKey(&k); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	k.Key = key
+	respondWithJSON(w, http.StatusCreated, k)
+}
+
+// deleteAPIKeyEndpoint revokes an API key.
+func (a *App) deleteAPIKeyEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := strconv.Atoi(ps.ByName("key_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid key ID")
+		return
+	}
+	if err := a.Store.DeleteAPIKey(&recipes.APIKey{ID: id}); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
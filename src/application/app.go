@@ -3,87 +3,131 @@ package application
 
 import (
 	// native packages
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"log"
 	"net/http"
 	"strconv"
-	"strings"
 
 	// local packages
 	"recipes"
 
 	// GitHub packages
-	"github.com/jmoiron/sqlx"
 	"github.com/julienschmidt/httprouter"
-	// Standard SQL Override
-	_ "github.com/lib/pq"
 )
 
-// App represents the application
+// App represents the application. TokenSecret is the key which the tokens
+// issued on login are signed with.
 type App struct {
-	Router *httprouter.Router
-	DB     *sqlx.DB
+	Router       *httprouter.Router
+	Store        recipes.Store
+	TokenSecret  []byte
+	authUser     string
+	authPassword string
 }
 
+// getRecipeEndpoint returns a recipe along with everything belonging to it,
+// tagged with its version (so clients may revalidate it by If-None-Match,
+// and modify it conditionally by If-Match).
 func (a *App) getRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// httprouter cannot route /v1/recipes/suggest alongside /v1/recipes/:id,
+	// so suggestions are dispatched from here (before the ID is parsed)
+	if ps.ByName("id") == "suggest" {
+		a.suggestRecipesEndpoint(w, req, ps)
+		return
+	}
 	id, err := strconv.Atoi(ps.ByName("id"))
 	if err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
 		return
 	}
-	r := recipes.Recipe{ID: id}
-	if err := r.GetRecipe(a.DB); err != nil {
-		switch err {
-		case sql.ErrNoRows:
-			respondWithError(w, http.StatusNotFound, "Recipe not found")
-		default:
-			respondWithError(w, http.StatusInternalServerError, err.Error())
+	var servings int
+	if req.FormValue("servings") != "" {
+		servings, err = strconv.Atoi(req.FormValue("servings"))
+		if err != nil || servings < 1 {
+			respondWithError(w, http.StatusBadRequest, "Invalid servings")
+			return
 		}
+	}
+	units := req.FormValue("units")
+	if units != "" && !recipes.IsValidSystem(units) {
+		respondWithError(w, http.StatusBadRequest, "Invalid units (expected metric or imperial)")
 		return
 	}
-	respondWithJSON(w, http.StatusOK, r)
-}
-
-func (a *App) getRecipesEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	count, _ := strconv.Atoi(req.FormValue("count"))
-	start, _ := strconv.Atoi(req.FormValue("start"))
-
-	if count > 10 || count < 1 {
-		count = 10
+	r := recipes.Recipe{ID: id}
+	if err := a.Store.GetRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
+		return
 	}
-	if start < 0 {
-		start = 0
+	etag := recipeETag(r.Version)
+	w.Header().Set("ETag", etag)
+	if header := req.Header.Get("If-None-Match"); header != "" && etagMatches(header, etag, true) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	ingredients, err := a.Store.GetIngredients(id)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	steps, err := a.Store.GetSteps(id)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
 	}
-	recipes, err := recipes.GetRecipes(a.DB, start, count)
+	tags, err := a.Store.GetRecipeTags(id)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+		respondWithStoreError(w, err)
 		return
 	}
-	respondWithJSON(w, http.StatusOK, recipes)
+	cuisines, err := a.Store.GetRecipeTerms(id, recipes.Cuisine)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	courses, err := a.Store.GetRecipeTerms(id, recipes.Course)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	if units != "" {
+		// Convert before scaling, as metric quantities are rounded more finely
+		ingredients = recipes.ConvertIngredients(ingredients, units)
+		steps = recipes.ConvertSteps(steps, units)
+	}
+	if servings > 0 {
+		if r.Servings == 0 {
+			respondWithError(w, http.StatusBadRequest, "Recipe does not specify servings, so cannot be scaled")
+			return
+		}
+		ingredients = recipes.ScaleIngredients(ingredients, r.Servings, servings)
+		r.Servings = servings
+	}
+	respondWithJSON(w, http.StatusOK, recipes.RecipeDetail{
+		Recipe:      r,
+		Ingredients: ingredients,
+		Steps:       steps,
+		Tags:        tags,
+		Cuisines:    cuisines,
+		Courses:     courses,
+	})
+}
+
+func (a *App) getRecipesEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	a.listRecipes(w, req, false)
 }
 
 func (a *App) createRecipeEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	var r recipes.Recipe
-	if req.Body == nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload (missing)")
-		return
-	}
-	decoder := json.NewDecoder(req.Body)
-	if err := decoder.Decode(&r); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	if !decodePayload(w, req, &r) {
 		return
 	}
-	defer req.Body.Close()
-	if err := r.CreateRecipe(a.DB); err != nil {
-		if strings.HasPrefix(err.Error(), "pq: duplicate") { // Hack
-			respondWithError(w, http.StatusConflict, err.Error())
-		} else {
-			respondWithError(w, http.StatusInternalServerError, err.Error())
-		}
+	r.Owner = requestUser(req)
+	r.ModifiedBy = requestUser(req)
+	if err := a.Store.CreateRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
 		return
 	}
+	w.Header().Set("ETag", recipeETag(r.Version))
 	respondWithJSON(w, http.StatusCreated, r)
 }
 
@@ -94,27 +138,21 @@ func (a *App) modifyRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps
 		return
 	}
 	var r recipes.Recipe
-	if req.Body == nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload (missing)")
+	if !decodePayload(w, req, &r) {
 		return
 	}
-	decoder := json.NewDecoder(req.Body)
-	if err := decoder.Decode(&r); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+	version, ok := a.ifMatch(w, req, id)
+	if !ok {
 		return
 	}
-	defer req.Body.Close()
 	r.ID = id
-	res, err := r.UpdateRecipe(a.DB)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	updated, _ := res.RowsAffected()
-	if updated == 0 {
-		respondWithError(w, http.StatusNotFound, "Recipe ID not found")
+	r.Version = version
+	r.ModifiedBy = requestUser(req)
+	if err := a.Store.UpdateRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
 		return
 	}
+	w.Header().Set("ETag", recipeETag(r.Version))
 	respondWithJSON(w, http.StatusOK, r)
 }
 
@@ -124,73 +162,26 @@ func (a *App) deleteRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps
 		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
 		return
 	}
-	r := recipes.Recipe{ID: id}
-	res, err := r.DeleteRecipe(a.DB)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
+	version, ok := a.ifMatch(w, req, id)
+	if !ok {
 		return
 	}
-	deleted, _ := res.RowsAffected()
-	if deleted == 0 {
-		respondWithError(w, http.StatusNotFound, "Recipe ID not found")
+	r := recipes.Recipe{ID: id, Version: version}
+	if err := a.Store.DeleteRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
 		return
 	}
 	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
 }
 
-func (a *App) addRatingEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-	recipeID, err := strconv.Atoi(ps.ByName("recipe_id"))
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
-		return
-	}
-	rr := recipes.RecipeRating{RecipeID: recipeID}
-	if req.Body == nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload (missing)")
-		return
-	}
-	decoder := json.NewDecoder(req.Body)
-	if err := decoder.Decode(&rr); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
-		return
-	}
-	defer req.Body.Close()
-	if err := rr.AddRecipeRating(a.DB); err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	respondWithJSON(w, http.StatusCreated, rr)
-}
-
 func (a *App) searchRecipesEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
-	count, _ := strconv.Atoi(req.FormValue("count"))
-	start, _ := strconv.Atoi(req.FormValue("start"))
-
-	var preptime32 float32
-	if req.FormValue("preptime") == "" {
-		preptime32 = 9999.99 // random large value
-	} else {
-		preptime64, _ := strconv.ParseFloat(req.FormValue("preptime"), 32)
-		preptime32 = float32(preptime64)
-	}
-
-	if count > 10 || count < 1 {
-		count = 10
-	}
-	if start < 0 {
-		start = 0
-	}
-
-	recipesRated, err := recipes.GetRecipesRated(a.DB, start, count, preptime32)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-	respondWithJSON(w, http.StatusOK, recipesRated)
+	a.listRecipes(w, req, true)
 }
 
+// respondWithError responds with a problem which is described by its status
+// (with the message as its detail).
 func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+	respondWithProblem(w, Problem{Type: "about:blank", Title: http.StatusText(code), Status: code, Detail: message})
 }
 
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
@@ -200,45 +191,95 @@ func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Write(response)
 }
 
-func basicAuth(h httprouter.Handle, requiredUser, requiredPassword string) httprouter.Handle {
+// Initialize sets up the database connection, router, and routes for the app.
+// Any outstanding schema migrations are applied on startup.
+func (a *App) Initialize(dbDriver, dbHost, dbUser, dbPassword, dbName, authUser, authPassword string) {
 
-	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
-		// Get the Basic Authentication credentials
-		user, password, hasAuth := req.BasicAuth()
+	store, err := OpenStore(dbDriver, dbHost, dbUser, dbPassword, dbName)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-		if hasAuth && user == requiredUser && password == requiredPassword {
-			// Delegate request to the given handle
-			h(w, req, ps)
-		} else {
-			// Request Basic Authentication otherwise
-			w.Header().Set("WWW-Authenticate", "Basic realm=Restricted")
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+	if m, ok := store.(recipes.Migrator); ok {
+		applied, err := m.MigrateUp()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %d: %s", migration.Version, migration.Description)
 		}
 	}
-}
-
-// Initialize sets up the database connection, router, and routes for the app
-func (a *App) Initialize(dbHost, dbUser, dbPassword, dbName, authUser, authPassword string) {
 
-	connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbName)
+	a.InitializeWithStore(store, authUser, authPassword)
+}
 
-	var err error
+// InitializeWithStore sets up the router and routes for the app, using the supplied store
+func (a *App) InitializeWithStore(store recipes.Store, authUser, authPassword string) {
 
-	a.DB, err = sqlx.Open("postgres", connectionString)
-	if err != nil {
-		log.Fatal(err)
+	a.Store = store
+	a.authUser, a.authPassword = authUser, authPassword
+	if len(a.TokenSecret) == 0 {
+		a.TokenSecret = randomTokenSecret()
 	}
 
 	a.Router = httprouter.New()
 
+	a.Router.POST("/v1/auth/register", a.registerEndpoint)
+	a.Router.POST("/v1/auth/login", a.loginEndpoint)
+	a.Router.POST("/v1/auth/refresh", a.refreshEndpoint)
 	a.Router.GET("/v1/recipes", a.getRecipesEndpoint)
-	a.Router.POST("/v1/recipes", basicAuth(a.createRecipeEndpoint, authUser, authPassword))
+	a.Router.POST("/v1/recipes", a.authorize(recipes.Contributor, a.createRecipeEndpoint))
 	a.Router.GET("/v1/recipes/:id", a.getRecipeEndpoint)
-	a.Router.PUT("/v1/recipes/:id", basicAuth(a.modifyRecipeEndpoint, authUser, authPassword))
-	a.Router.PATCH("/v1/recipes/:id", basicAuth(a.modifyRecipeEndpoint, authUser, authPassword))
-	a.Router.DELETE("/v1/recipes/:id", basicAuth(a.deleteRecipeEndpoint, authUser, authPassword))
-	a.Router.POST("/v1/recipes/:recipe_id/rating", a.addRatingEndpoint)
+	a.Router.PUT("/v1/recipes/:id", a.authorizeRecipe(a.modifyRecipeEndpoint))
+	a.Router.PATCH("/v1/recipes/:id", a.authorizeRecipe(a.patchRecipeEndpoint))
+	a.Router.DELETE("/v1/recipes/:id", a.authorizeRecipe(a.deleteRecipeEndpoint))
+	a.Router.POST("/v1/recipes/:id/restore", a.authorize(recipes.Editor, a.restoreRecipeEndpoint))
+	a.Router.GET("/v1/recipes/:id/ratings", a.getRatingsEndpoint)
+	a.Router.GET("/v1/recipes/:id/rating", a.identify(a.getRatingEndpoint))
+	a.Router.POST("/v1/recipes/:id/rating", a.identify(a.setRatingEndpoint))
+	a.Router.PUT("/v1/recipes/:id/rating", a.identify(a.setRatingEndpoint))
+	a.Router.DELETE("/v1/recipes/:id/rating", a.identify(a.deleteRatingEndpoint))
+	a.Router.GET("/v1/recipes/:id/reviews", a.identify(a.getReviewsEndpoint))
+	a.Router.POST("/v1/recipes/:id/reviews", a.authorize(recipes.Contributor, a.addReviewEndpoint))
+	a.Router.GET("/v1/recipes/:id/reviews/:review_id", a.identify(a.getReviewEndpoint))
+	a.Router.PUT("/v1/recipes/:id/reviews/:review_id", a.authorize(recipes.Contributor, a.modifyReviewEndpoint))
+	a.Router.DELETE("/v1/recipes/:id/reviews/:review_id", a.authorize(recipes.Contributor, a.deleteReviewEndpoint))
+	a.Router.POST("/v1/recipes/:id/reviews/:review_id/flag", a.authenticate(a.flagReviewEndpoint))
+	a.Router.POST("/v1/recipes/:id/reviews/:review_id/hide", a.authorize(recipes.Admin, a.setReviewHiddenEndpoint(true)))
+	a.Router.POST("/v1/recipes/:id/reviews/:review_id/unhide", a.authorize(recipes.Admin, a.setReviewHiddenEndpoint(false)))
+	a.Router.GET("/v1/recipes/:id/ingredients", a.getIngredientsEndpoint)
+	a.Router.POST("/v1/recipes/:id/ingredients", a.authorizeRecipe(a.addIngredientEndpoint))
+	a.Router.GET("/v1/recipes/:id/ingredients/:ingredient_id", a.getIngredientEndpoint)
+	a.Router.PUT("/v1/recipes/:id/ingredients/:ingredient_id", a.authorizeRecipe(a.modifyIngredientEndpoint))
+	a.Router.DELETE("/v1/recipes/:id/ingredients/:ingredient_id", a.authorizeRecipe(a.deleteIngredientEndpoint))
+	a.Router.GET("/v1/recipes/:id/steps", a.getStepsEndpoint)
+	a.Router.POST("/v1/recipes/:id/steps", a.authorizeRecipe(a.addStepEndpoint))
+	a.Router.POST("/v1/recipes/:id/steps/reorder", a.authorizeRecipe(a.reorderStepsEndpoint))
+	a.Router.GET("/v1/recipes/:id/steps/:step_id", a.getStepEndpoint)
+	a.Router.PUT("/v1/recipes/:id/steps/:step_id", a.authorizeRecipe(a.modifyStepEndpoint))
+	a.Router.DELETE("/v1/recipes/:id/steps/:step_id", a.authorizeRecipe(a.deleteStepEndpoint))
+	a.Router.GET("/v1/recipes/:id/revisions", a.getRevisionsEndpoint)
+	a.Router.GET("/v1/recipes/:id/revisions/:revision", a.getRevisionEndpoint)
+	a.Router.POST("/v1/recipes/:id/revisions/:revision/restore", a.authorizeRecipe(a.restoreRevisionEndpoint))
+	a.Router.GET("/v1/recipes/:id/tags", a.getRecipeTagsEndpoint)
+	a.Router.PUT("/v1/recipes/:id/tags", a.authorizeRecipe(a.setRecipeTagsEndpoint))
+	a.Router.GET("/v1/tags", a.getTagsEndpoint)
+	for _, taxonomy := range recipes.Taxonomies {
+		collection := taxonomyCollections[taxonomy]
+		a.Router.GET("/v1/recipes/:id/"+collection, a.getRecipeTermsEndpoint(taxonomy))
+		a.Router.PUT("/v1/recipes/:id/"+collection, a.authorizeRecipe(a.setRecipeTermsEndpoint(taxonomy)))
+		a.Router.GET("/v1/"+collection, a.getTermsEndpoint(taxonomy))
+		a.Router.POST("/v1/"+collection, a.authorize(recipes.Editor, a.addTermEndpoint(taxonomy)))
+		a.Router.DELETE("/v1/"+collection+"/:term_id", a.authorize(recipes.Editor, a.deleteTermEndpoint(taxonomy)))
+	}
+	a.Router.GET("/v1/trash", a.authorize(recipes.Editor, a.getTrashEndpoint))
+	a.Router.DELETE("/v1/trash/:id", a.authorize(recipes.Admin, a.purgeRecipeEndpoint))
+	a.Router.PUT("/v1/users/:username/role", a.authorize(recipes.Admin, a.setUserRoleEndpoint))
+	a.Router.GET("/v1/apikeys", a.authorize(recipes.Admin, a.getAPIKeysEndpoint))
+	a.Router.POST("/v1/apikeys", a.authorize(recipes.Admin, a.createAPIKeyEndpoint))
+	a.Router.DELETE("/v1/apikeys/:key_id", a.authorize(recipes.Admin, a.deleteAPIKeyEndpoint))
 	a.Router.POST("/v1/search/recipes", a.searchRecipesEndpoint)
+	// GET /v1/recipes/suggest is dispatched by getRecipeEndpoint
 }
 
 // Run starts the app and serves on the specified port
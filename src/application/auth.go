@@ -0,0 +1,320 @@
+package application
+
+import (
+	// native packages
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/golang-jwt/jwt"
+	"github.com/julienschmidt/httprouter"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// How long the tokens issued on login remain valid. An access token is sent
+// with each request, and a refresh token exchanged for new tokens when it
+// expires (so the password need not be kept by the client).
+const (
+	accessTokenLifetime  = 15 * time.Minute
+	refreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+// The uses of a token, which are not interchangeable.
+const (
+	accessToken  = "access"
+	refreshToken = "refresh"
+)
+
+// errUnauthenticated is returned when a request does not have the (valid)
+// credentials of a user.
+var errUnauthenticated = errors.New("authentication required")
+
+// errInvalidToken is returned for a token which is malformed, not signed by
+// the app, of the wrong use or expired.
+var errInvalidToken = errors.New("invalid token")
+
+// tokenClaims are the claims of a token (a JWT, signed by HMAC SHA-256 with
+// the app's TokenSecret): whose it is (their username, as the subject, and
+// whether they are the configured user rather than a registered one), what
+// it may be used for, and when it was issued and expires.
+type tokenClaims struct {
+	Configured bool   `json:"cfg,omitempty"`
+	Use        string `json:"use"`
+	jwt.StandardClaims
+}
+
+// The tokens entity is used to marshall the tokens issued on login (or
+// refresh). ExpiresIn is the lifetime of the access token, in seconds.
+type tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// The registration entity is used to unmarshall a new user account (bcrypt
+// only uses the first 72 bytes of a password).
+type registration struct {
+	Username string `json:"username" validate:"required,max=50"`
+	Password string `json:"password" validate:"min=8,max=72"`
+}
+
+// The login entity is used to unmarshall the credentials of a user.
+type login struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+// contextKey is the type of the keys of values added to a request's context.
+type contextKey int
+
+// userKey is the key of the authenticated user.
+const userKey contextKey = 0
+
+// randomTokenSecret returns a random key to sign tokens with, when none has
+// been configured (so tokens do not outlive the app).
+func randomTokenSecret() []byte {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal(err)
+	}
+	log.Print("No token secret configured, so tokens will not outlive this instance")
+	return secret
+}
+
+// signToken returns a token for the claims, signed with the secret.
+func signToken(secret []byte, claims tokenClaims) string {
+	// signing with an HMAC key only fails if the key is not a []byte
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	return token
+}
+
+// parseToken returns the claims of a token which was signed with the secret,
+// has not expired and is valid for the use given.
+func parseToken(secret []byte, token, use string) (tokenClaims, error) {
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		// only the algorithm we sign with is accepted (so never "none")
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, errInvalidToken
+		}
+		return secret, nil
+	})
+	// the library only checks the expiry of tokens which have one
+	if err != nil || claims.Use != use || claims.Subject == "" || claims.ExpiresAt == 0 {
+		return claims, errInvalidToken
+	}
+	return claims, nil
+}
+
+// issueTokens returns new access and refresh tokens for a user (who is the
+// configured user only if they presented the configured pair).
+func (a *App) issueTokens(username string, configured bool) tokens {
+	now := time.Now()
+	return tokens{
+		AccessToken: signToken(a.TokenSecret, tokenClaims{Configured: configured, Use: accessToken,
+			StandardClaims: jwt.StandardClaims{Subject: username, IssuedAt: now.Unix(), ExpiresAt: now.Add(accessTokenLifetime).Unix()}}),
+		RefreshToken: signToken(a.TokenSecret, tokenClaims{Configured: configured, Use: refreshToken,
+			StandardClaims: jwt.StandardClaims{Subject: username, IssuedAt: now.Unix(), ExpiresAt: now.Add(refreshTokenLifetime).Unix()}}),
+		TokenType: "Bearer",
+		ExpiresIn: int(accessTokenLifetime / time.Second),
+	}
+}
+
+// checkPassword returns the registered user with the credentials given.
+func (a *App) checkPassword(username, password string) (recipes.User, error) {
+	u := recipes.User{Username: username}
+	if err := a.Store.GetUser(&u); err != nil {
+		return u, err
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return u, recipes.ErrUserNotFound
+	}
+	return u, nil
+}
+
+// configuredUser returns the configured user (an admin). Only requests which
+// present the configured pair (or tokens issued for it) are theirs.
+func (a *App) configuredUser() recipes.User {
+	return recipes.User{Username: a.authUser, Role: recipes.Admin}
+}
+
+// isConfiguredPair reports whether credentials are the configured pair.
+func (a *App) isConfiguredPair(username, password string) bool {
+	return a.authUser != "" && username == a.authUser &&
+		hmac.Equal([]byte(password), []byte(a.authPassword))
+}
+
+// user returns the registered user with a username.
+func (a *App) user(username string) (recipes.User, error) {
+	u := recipes.User{Username: username}
+	err := a.Store.GetUser(&u)
+	if err == recipes.ErrUserNotFound {
+		err = errUnauthenticated
+	}
+	return u, err
+}
+
+// tokenUser returns the user a token was issued to: the configured user if
+// it was issued for the configured pair, and otherwise the registered user.
+func (a *App) tokenUser(claims tokenClaims) (recipes.User, error) {
+	if claims.Configured {
+		if a.authUser == "" || claims.Subject != a.authUser {
+			return recipes.User{}, errUnauthenticated
+		}
+		return a.configuredUser(), nil
+	}
+	return a.user(claims.Subject)
+}
+
+// authenticatedUser returns the user making a request, who may present an
+// API key (as X-API-Key, or as a bearer token), an access token, or basic
+// credentials (either the configured pair or those of a registered user).
+func (a *App) authenticatedUser(req *http.Request) (recipes.User, error) {
+	if key := req.Header.Get("X-API-Key"); key != "" {
+		return a.apiKeyUser(key)
+	}
+	if header := req.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token := strings.TrimPrefix(header, "Bearer ")
+		if strings.HasPrefix(token, apiKeyPrefix) {
+			return a.apiKeyUser(token)
+		}
+		claims, err := parseToken(a.TokenSecret, token, accessToken)
+		if err != nil {
+			return recipes.User{}, errUnauthenticated
+		}
+		return a.tokenUser(claims)
+	}
+	user, password, hasAuth := req.BasicAuth()
+	if !hasAuth {
+		return recipes.User{}, errUnauthenticated
+	}
+	if a.isConfiguredPair(user, password) {
+		return a.configuredUser(), nil
+	}
+	u, err := a.checkPassword(user, password)
+	if err == recipes.ErrUserNotFound {
+		err = errUnauthenticated
+	}
+	return u, err
+}
+
+// authenticate wraps a handle which requires the user to be authenticated,
+// adding them to the request's context (see requestUser and requestRole).
+func (a *App) authenticate(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		u, err := a.authenticatedUser(req)
+		if err == errUnauthenticated {
+			w.Header().Add("WWW-Authenticate", "Bearer realm=Restricted")
+			w.Header().Add("WWW-Authenticate", "Basic realm=Restricted")
+			respondWithError(w, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if err != nil {
+			respondWithStoreError(w, err)
+			return
+		}
+		h(w, req.WithContext(context.WithValue(req.Context(), userKey, u)), ps)
+	}
+}
+
+// identify wraps a handle which anyone may use, adding the user to the
+// request's context if they present credentials (which must be valid).
+func (a *App) identify(h httprouter.Handle) httprouter.Handle {
+	authenticated := a.authenticate(h)
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		if req.Header.Get("X-API-Key") != "" || req.Header.Get("Authorization") != "" {
+			authenticated(w, req, ps)
+			return
+		}
+		h(w, req, ps)
+	}
+}
+
+// requestUser returns the name of the user making a request (or "" if anonymous).
+func requestUser(req *http.Request) string {
+	u, _ := req.Context().Value(userKey).(recipes.User)
+	return u.Username
+}
+
+// requestRole returns the role of the user making a request (or "" if anonymous).
+func requestRole(req *http.Request) string {
+	u, _ := req.Context().Value(userKey).(recipes.User)
+	return u.Role
+}
+
+func (a *App) registerEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var r registration
+	if !decodePayload(w, req, &r) {
+		return
+	}
+	if a.authUser != "" && r.Username == a.authUser {
+		// the configured user's name is reserved for them
+		respondWithStoreError(w, recipes.ErrDuplicateUser)
+		return
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(r.Password), bcrypt.DefaultCost)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	u := recipes.User{Username: r.Username, PasswordHash: string(hash)}
+	if err := a.Store.CreateUser(&u); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, u)
+}
+
+// loginEndpoint issues tokens to a registered user, or the configured user.
+func (a *App) loginEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var l login
+	if !decodePayload(w, req, &l) {
+		return
+	}
+	if a.isConfiguredPair(l.Username, l.Password) {
+		respondWithJSON(w, http.StatusOK, a.issueTokens(l.Username, true))
+		return
+	}
+	if _, err := a.checkPassword(l.Username, l.Password); err != nil {
+		if err == recipes.ErrUserNotFound {
+			respondWithError(w, http.StatusUnauthorized, "Invalid username or password")
+		} else {
+			respondWithStoreError(w, err)
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, a.issueTokens(l.Username, false))
+}
+
+// refreshEndpoint exchanges a refresh token for new tokens (as long as the
+// registered user still exists, or the token was issued to the configured user).
+func (a *App) refreshEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var payload struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	claims, err := parseToken(a.TokenSecret, payload.RefreshToken, refreshToken)
+	if err == nil {
+		_, err = a.tokenUser(claims)
+	}
+	switch err {
+	case nil:
+		respondWithJSON(w, http.StatusOK, a.issueTokens(claims.Subject, claims.Configured))
+	case errInvalidToken, errUnauthenticated:
+		respondWithError(w, http.StatusUnauthorized, "Invalid refresh token")
+	default:
+		respondWithStoreError(w, err)
+	}
+}
@@ -0,0 +1,96 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// The roleChange entity is used to unmarshall a new role for a user.
+type roleChange struct {
+	Role string `json:"role" validate:"oneof=viewer contributor editor admin"`
+}
+
+// roleRank ranks a role among the Roles (-1 if it is none of them).
+func roleRank(role string) int {
+	for rank, r := range recipes.Roles {
+		if r == role {
+			return rank
+		}
+	}
+	return -1
+}
+
+// hasRole reports whether a role is (at least) as privileged as the role required.
+func hasRole(role, required string) bool {
+	return roleRank(role) >= 0 && roleRank(role) >= roleRank(required)
+}
+
+// respondWithForbidden responds that the user may not do what they asked.
+func respondWithForbidden(w http.ResponseWriter, detail string) {
+	respondWithProblem(w, Problem{
+		Type:   forbiddenProblem,
+		Title:  "Forbidden",
+		Status: http.StatusForbidden,
+		Detail: detail,
+	})
+}
+
+// authorize wraps a handle which requires the user to be authenticated, and
+// to have (at least) the role given.
+func (a *App) authorize(role string, h httprouter.Handle) httprouter.Handle {
+	return a.authenticate(func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		if !hasRole(requestRole(req), role) {
+			respondWithForbidden(w, "Only users with the "+role+" role (or above) may do this")
+			return
+		}
+		h(w, req, ps)
+	})
+}
+
+// authorizeRecipe wraps a handle which modifies a recipe (by the ID in the
+// path) or something belonging to it. Contributors may only modify their
+// own recipes, and editors (or admins) any recipe.
+func (a *App) authorizeRecipe(h httprouter.Handle) httprouter.Handle {
+	return a.authorize(recipes.Contributor, func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		if hasRole(requestRole(req), recipes.Editor) {
+			h(w, req, ps)
+			return
+		}
+		id, err := strconv.Atoi(ps.ByName("id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+			return
+		}
+		r := recipes.Recipe{ID: id}
+		if err := a.Store.GetRecipe(&r); err != nil {
+			respondWithStoreError(w, err)
+			return
+		}
+		if r.Owner != requestUser(req) {
+			respondWithForbidden(w, "Only the owner of a recipe (or an editor) may modify it")
+			return
+		}
+		h(w, req, ps)
+	})
+}
+
+// setUserRoleEndpoint gives a user another role.
+func (a *App) setUserRoleEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	var payload roleChange
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	u := recipes.User{Username: ps.ByName("username"), Role: payload.Role}
+	if err := a.Store.SetUserRole(&u); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, u)
+}
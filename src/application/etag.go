@@ -0,0 +1,66 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+	"strings"
+
+	// local packages
+	"recipes"
+)
+
+// recipeETag returns the entity tag of a version of a recipe. It is strong,
+// as the version advances whenever anything shown along with the recipe changes.
+func recipeETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// etagMatches reports whether an If-Match or If-None-Match header lists the
+// entity tag (or is "*"). Weak comparison (RFC 7232) ignores the weakness of
+// the tags listed, while strong comparison never matches a weak tag.
+func etagMatches(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" {
+			return true
+		}
+		if strings.HasPrefix(tag, "W/") {
+			if !weak {
+				continue
+			}
+			tag = tag[2:]
+		}
+		if tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatch evaluates the If-Match header of a request to modify a recipe,
+// responding with an error (and returning false) if the recipe is not found,
+// or is no longer a version that the client expects. Otherwise it returns
+// the version to be modified, which is 0 if the request is unconditional.
+func (a *App) ifMatch(w http.ResponseWriter, req *http.Request, id int) (version int, ok bool) {
+	header := req.Header.Get("If-Match")
+	if header == "" {
+		return 0, true
+	}
+	r := recipes.Recipe{ID: id}
+	if err := a.Store.GetRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
+		return 0, false
+	}
+	if !etagMatches(header, recipeETag(r.Version), false) {
+		respondWithPreconditionFailed(w)
+		return 0, false
+	}
+	return r.Version, true
+}
+
+// respondWithPreconditionFailed responds that the recipe has been modified
+// since the client last read it.
+func respondWithPreconditionFailed(w http.ResponseWriter) {
+	respondWithProblem(w, storeProblems[recipes.ErrVersionConflict])
+}
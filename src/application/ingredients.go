@@ -0,0 +1,111 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// ingredientParams parses the recipe and ingredient IDs from the path.
+func ingredientParams(w http.ResponseWriter, ps httprouter.Params) (recipes.Ingredient, bool) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return recipes.Ingredient{}, false
+	}
+	ingredientID, err := strconv.Atoi(ps.ByName("ingredient_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid ingredient ID")
+		return recipes.Ingredient{}, false
+	}
+	return recipes.Ingredient{ID: ingredientID, RecipeID: recipeID}, true
+}
+
+// respondWithIngredientError maps ingredient storage errors to responses.
+func respondWithIngredientError(w http.ResponseWriter, err error) {
+	switch err {
+	case recipes.ErrCheckViolation:
+		respondWithError(w, http.StatusBadRequest, "Invalid ingredient (item is required, quantity must not be negative)")
+	default:
+		respondWithStoreError(w, err)
+	}
+}
+
+func (a *App) getIngredientsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	ingredients, err := a.Store.GetIngredients(recipeID)
+	if err != nil {
+		respondWithIngredientError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, ingredients)
+}
+
+func (a *App) getIngredientEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	i, ok := ingredientParams(w, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.GetIngredient(&i); err != nil {
+		respondWithIngredientError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, i)
+}
+
+func (a *App) addIngredientEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	var i recipes.Ingredient
+	if !decodePayload(w, req, &i) {
+		return
+	}
+	i.RecipeID = recipeID
+	if err := a.Store.AddIngredient(&i); err != nil {
+		respondWithIngredientError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, i)
+}
+
+func (a *App) modifyIngredientEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	ids, ok := ingredientParams(w, ps)
+	if !ok {
+		return
+	}
+	var i recipes.Ingredient
+	if !decodePayload(w, req, &i) {
+		return
+	}
+	i.ID, i.RecipeID = ids.ID, ids.RecipeID
+	if err := a.Store.UpdateIngredient(&i); err != nil {
+		respondWithIngredientError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, i)
+}
+
+func (a *App) deleteIngredientEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	i, ok := ingredientParams(w, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.DeleteIngredient(&i); err != nil {
+		respondWithIngredientError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
@@ -0,0 +1,55 @@
+package application
+
+import (
+	// native packages
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	// local packages
+	"recipes"
+)
+
+// Migrate runs a schema migration command ("up", "down" or "status")
+// against the supplied store, reporting the results to out.
+func Migrate(out io.Writer, store recipes.Store, command string) error {
+	m, ok := store.(recipes.Migrator)
+	if !ok {
+		return errors.New("this database driver does not have a schema to migrate")
+	}
+
+	switch command {
+	case "up":
+		applied, err := m.MigrateUp()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "Applied migration %d: %s\n", migration.Version, migration.Description)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "No outstanding migrations")
+		}
+		return err
+	case "down":
+		reverted, err := m.MigrateDown()
+		if reverted != nil {
+			fmt.Fprintf(out, "Reverted migration %d: %s\n", reverted.Version, reverted.Description)
+		} else if err == nil {
+			fmt.Fprintln(out, "No migrations to revert")
+		}
+		return err
+	case "status":
+		status, err := m.MigrationStatus()
+		if err != nil {
+			return err
+		}
+		for _, ms := range status {
+			state := "pending"
+			if ms.AppliedAt != nil {
+				state = "applied " + ms.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(out, "%4d  %-40s %s\n", ms.Version, ms.Description, state)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown migrate command %q (expected up, down or status)", command)
+}
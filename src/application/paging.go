@@ -0,0 +1,182 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	// local packages
+	"recipes"
+)
+
+// maxLimit is the largest page of recipes which may be requested by limit
+// (count, the original parameter, is clamped to 10).
+const maxLimit = 100
+
+// recipeList is the envelope in which a page of recipes is returned to
+// clients paging by cursor (or requesting facets). Next and Prev are the
+// cursors of the neighbouring pages, and are omitted where there is no such page.
+type recipeList struct {
+	Recipes interface{}     `json:"recipes"`
+	Total   int             `json:"total"`
+	Next    string          `json:"next,omitempty"`
+	Prev    string          `json:"prev,omitempty"`
+	Facets  *recipes.Facets `json:"facets,omitempty"`
+}
+
+// pageRequest parses the paging parameters of a request, responding with an
+// error (and returning false) if any is invalid. A client pages by cursor
+// if it passes either a cursor or a limit (an empty cursor requesting the
+// first page), and otherwise by start and count.
+func pageRequest(w http.ResponseWriter, req *http.Request) (p recipes.Page, byCursor bool, ok bool) {
+	parseForm(req)
+	_, hasCursor := req.Form["cursor"]
+	_, hasLimit := req.Form["limit"]
+	if !hasCursor && !hasLimit {
+		p.Start, p.Count, ok = pageParams(w, req)
+		return p, false, ok
+	}
+	limit, ok := optionalInt(w, req, "limit", 1, maxLimit)
+	if !ok {
+		return p, true, false
+	}
+	p.Count = 10
+	if limit != nil {
+		p.Count = *limit
+	}
+	if token := req.Form.Get("cursor"); token != "" {
+		c, err := recipes.ParseCursor(token)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid cursor")
+			return p, true, false
+		}
+		p.Cursor = c
+	}
+	return p, true, true
+}
+
+// listRecipes responds with a page of the recipes which match the filters of
+// the request, in the order requested, with or without their ratings.
+// Clients paging by cursor, or requesting the facets of rated recipes,
+// receive the page in a recipeList, while those paging by start and count
+// receive a bare array, as they always have.
+// Either way, the total number of recipes is returned in the X-Total-Count
+// header, and the neighbouring pages are linked (RFC 5988).
+func (a *App) listRecipes(w http.ResponseWriter, req *http.Request, rated bool) {
+	p, byCursor, ok := pageRequest(w, req)
+	if !ok {
+		return
+	}
+	filter, ok := recipeFilter(w, req)
+	if !ok {
+		return
+	}
+	order, ok := recipeSort(w, req)
+	if !ok {
+		return
+	}
+	// a cursor carries its own sort order, which cannot change between pages
+	if p.Cursor != nil && req.Form.Get("sort") != "" && order != p.Cursor.Sort {
+		respondWithError(w, http.StatusBadRequest, "Invalid cursor (it was issued for sort="+p.Cursor.Sort.String()+")")
+		return
+	}
+	withFacets := false
+	if value := req.Form.Get("facets"); rated && value != "" {
+		var err error
+		if withFacets, err = strconv.ParseBool(value); err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid facets (expected true or false)")
+			return
+		}
+	}
+	page, err := a.Store.GetRecipesPage(filter, order, p)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	var facets *recipes.Facets
+	if withFacets {
+		f, err := a.Store.GetRecipeFacets(filter)
+		if err != nil {
+			respondWithStoreError(w, err)
+			return
+		}
+		facets = &f
+	}
+
+	var payload interface{} = page.Recipes
+	if !rated {
+		list := []recipes.Recipe{}
+		for _, rr := range page.Recipes {
+			list = append(list, rr.Recipe)
+		}
+		payload = list
+	}
+	if byCursor || withFacets {
+		list := recipeList{Recipes: payload, Total: page.Total, Facets: facets}
+		if page.Next != nil {
+			list.Next = page.Next.Token()
+		}
+		if page.Prev != nil {
+			list.Prev = page.Prev.Token()
+		}
+		payload = list
+	}
+	w.Header().Set("Link", pageLinks(req, p, page, byCursor))
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	respondWithJSON(w, http.StatusOK, payload)
+}
+
+// pageLink returns a link (for a Link header) to another page of whatever
+// the request is for, with the paging parameters given. Every other parameter
+// of the request (including any in its body) is carried in its query.
+func pageLink(req *http.Request, rel string, paging map[string]string) string {
+	values := url.Values{}
+	for name, value := range req.Form {
+		switch name {
+		case "start", "count", "cursor", "limit":
+		default:
+			values[name] = value
+		}
+	}
+	for name, value := range paging {
+		values.Set(name, value)
+	}
+	return "<" + req.URL.Path + "?" + values.Encode() + `>; rel="` + rel + `"`
+}
+
+// pageLinks returns the Link header locating the first, previous and next
+// pages of recipes, paged in the same way as the request.
+func pageLinks(req *http.Request, p recipes.Page, page recipes.RecipePage, byCursor bool) string {
+	link := func(rel string, paging map[string]string) string {
+		return pageLink(req, rel, paging)
+	}
+
+	var links []string
+	if byCursor {
+		limit := strconv.Itoa(p.Count)
+		links = append(links, link("first", map[string]string{"limit": limit}))
+		if page.Prev != nil {
+			links = append(links, link("prev", map[string]string{"limit": limit, "cursor": page.Prev.Token()}))
+		}
+		if page.Next != nil {
+			links = append(links, link("next", map[string]string{"limit": limit, "cursor": page.Next.Token()}))
+		}
+		return strings.Join(links, ", ")
+	}
+
+	count := strconv.Itoa(p.Count)
+	links = append(links, link("first", map[string]string{"start": "0", "count": count}))
+	if page.Prev != nil {
+		prev := p.Start - p.Count
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, link("prev", map[string]string{"start": strconv.Itoa(prev), "count": count}))
+	}
+	if page.Next != nil {
+		links = append(links, link("next", map[string]string{"start": strconv.Itoa(p.Start + p.Count), "count": count}))
+	}
+	return strings.Join(links, ", ")
+}
@@ -0,0 +1,408 @@
+package application
+
+import (
+	// native packages
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// The media types of the patches accepted by PATCH. Plain JSON is taken to
+// be a merge patch (so clients sending a complete recipe are unaffected).
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+var (
+	// errInvalidPatch is returned when a JSON Patch is malformed.
+	errInvalidPatch = errors.New("invalid patch")
+	// errPatchTestFailed is returned when a JSON Patch "test" operation fails.
+	errPatchTestFailed = errors.New("patch test failed")
+
+	// The reasons a JSON Patch operation cannot be applied, which are
+	// reported against the member of the operation at fault.
+	errPatchPath       = errors.New("does not exist")
+	errPatchOp         = errors.New("is not a known op")
+	errPatchPointer    = errors.New("must be a JSON Pointer")
+	errPatchValue      = errors.New("is required")
+	errPatchMoveInto   = errors.New("cannot be moved into itself")
+	errPatchRemoveRoot = errors.New("cannot remove the whole document")
+)
+
+// patchError is returned when an operation of a JSON Patch cannot be applied.
+// Its field is a JSON Pointer to the member of the patch at fault.
+type patchError struct {
+	field string
+	err   error
+}
+
+func (e *patchError) Error() string {
+	return e.field + " " + e.err.Error()
+}
+
+// patchRecipeEndpoint applies a JSON Merge Patch (RFC 7396) or a JSON Patch
+// (RFC 6902) to a recipe, so only the fields supplied are changed. The
+// patched recipe only replaces the version which was patched, so concurrent
+// modifications are never lost.
+func (a *App) patchRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	if req.Body == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload (missing)")
+		return
+	}
+	defer req.Body.Close()
+	mediaType := "application/json"
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+	}
+	if mediaType != "application/json" && mediaType != mergePatchType && mediaType != jsonPatchType {
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		respondWithError(w, http.StatusUnsupportedMediaType,
+			"Unsupported patch format (expected "+mergePatchType+" or "+jsonPatchType+")")
+		return
+	}
+	var patch interface{}
+	if err := json.NewDecoder(req.Body).Decode(&patch); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	r := recipes.Recipe{ID: id}
+	if err := a.Store.GetRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	header := req.Header.Get("If-Match")
+	if header != "" && !etagMatches(header, recipeETag(r.Version), false) {
+		respondWithPreconditionFailed(w)
+		return
+	}
+	var doc interface{}
+	b, _ := json.Marshal(r)
+	json.Unmarshal(b, &doc)
+	if mediaType == jsonPatchType {
+		doc, err = applyJSONPatch(doc, patch)
+	} else {
+		doc = applyMergePatch(doc, patch)
+	}
+	switch err {
+	case nil:
+	case errInvalidPatch:
+		respondWithError(w, http.StatusBadRequest, "Invalid patch (expected an array of operations, each with an op and a path)")
+		return
+	case errPatchTestFailed:
+		respondWithError(w, http.StatusConflict, "Patch test failed")
+		return
+	default:
+		if pe, ok := err.(*patchError); ok {
+			respondWithValidationErrors(w, []FieldError{{Field: pe.field, Message: pe.err.Error()}})
+		} else {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid patch")
+		}
+		return
+	}
+
+	// the patched document must still be a recipe
+	patched := recipes.Recipe{}
+	b, _ = json.Marshal(doc)
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		if errs := decodeErrors(err); errs != nil {
+			respondWithValidationErrors(w, errs)
+		} else {
+			respondWithError(w, http.StatusUnprocessableEntity, "Invalid patch (the result is not a valid recipe)")
+		}
+		return
+	}
+	if errs := validate(&patched); errs != nil {
+		respondWithValidationErrors(w, errs)
+		return
+	}
+	patched.ID = id
+	patched.Version = r.Version
+	patched.ModifiedBy = requestUser(req)
+	if err := a.Store.UpdateRecipe(&patched); err != nil {
+		switch {
+		case err == recipes.ErrVersionConflict && header == "":
+			respondWithError(w, http.StatusConflict, "Recipe was modified while being patched (try again)")
+		case err == recipes.ErrCheckViolation:
+			respondWithError(w, http.StatusUnprocessableEntity,
+				"Invalid patch (difficulty must be 1, 2 or 3, and servings must not be negative)")
+		default:
+			respondWithStoreError(w, err)
+		}
+		return
+	}
+	w.Header().Set("ETag", recipeETag(patched.Version))
+	respondWithJSON(w, http.StatusOK, patched)
+}
+
+// applyMergePatch applies a JSON Merge Patch to a document: the members of
+// the patch replace those of the document (recursively, for objects), and
+// null members remove them.
+func applyMergePatch(doc, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	d, ok := doc.(map[string]interface{})
+	if !ok {
+		d = map[string]interface{}{}
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(d, name)
+		} else {
+			d[name] = applyMergePatch(d[name], value)
+		}
+	}
+	return d
+}
+
+// applyJSONPatch applies each operation of a JSON Patch to a document, in
+// turn. The document is left incomplete if any operation fails, so the
+// caller must discard it.
+func applyJSONPatch(doc, patch interface{}) (interface{}, error) {
+	ops, ok := patch.([]interface{})
+	if !ok {
+		return nil, errInvalidPatch
+	}
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, errInvalidPatch
+		}
+		var member string
+		var err error
+		if doc, member, err = applyPatchOperation(doc, op); err != nil {
+			if err == errInvalidPatch || err == errPatchTestFailed {
+				return nil, err
+			}
+			return nil, &patchError{field: "/" + strconv.Itoa(i) + "/" + member, err: err}
+		}
+	}
+	return doc, nil
+}
+
+// applyPatchOperation applies a single JSON Patch operation to a document,
+// returning the updated document or else the member of the operation at
+// fault along with why.
+func applyPatchOperation(doc interface{}, op map[string]interface{}) (interface{}, string, error) {
+	name, _ := op["op"].(string)
+	path, err := patchPointer(op, "path")
+	if err != nil {
+		return nil, "path", err
+	}
+	value, hasValue := op["value"]
+	switch name {
+	case "add", "replace", "test":
+		if !hasValue {
+			return nil, "value", errPatchValue
+		}
+	case "move", "copy":
+		from, err := patchPointer(op, "from")
+		if err != nil {
+			return nil, "from", err
+		}
+		if value, err = pointerGet(doc, from); err != nil {
+			return nil, "from", err
+		}
+		if name == "move" {
+			if isPointerPrefix(from, path) {
+				return nil, "from", errPatchMoveInto
+			}
+			if doc, err = pointerRemove(doc, from); err != nil {
+				return nil, "from", err
+			}
+		} else {
+			value = deepCopy(value)
+		}
+	case "remove":
+	default:
+		return nil, "op", errPatchOp
+	}
+
+	switch name {
+	case "add", "move", "copy":
+		doc, err = pointerPut(doc, path, value, true)
+	case "replace":
+		doc, err = pointerPut(doc, path, value, false)
+	case "remove":
+		doc, err = pointerRemove(doc, path)
+	case "test":
+		var current interface{}
+		if current, err = pointerGet(doc, path); err == nil && !reflect.DeepEqual(current, value) {
+			err = errPatchTestFailed
+		}
+	}
+	return doc, "path", err
+}
+
+// patchPointer parses a JSON Pointer (RFC 6901) member of a patch operation
+// into its reference tokens.
+func patchPointer(op map[string]interface{}, member string) ([]string, error) {
+	pointer, ok := op[member].(string)
+	if !ok {
+		return nil, errInvalidPatch
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, errPatchPointer
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
+
+// isPointerPrefix reports whether the pointer a is a proper prefix of b.
+func isPointerPrefix(a, b []string) bool {
+	return len(a) < len(b) && reflect.DeepEqual(a, b[:len(a)])
+}
+
+// arrayIndex parses a reference token as an index into an array of the
+// specified length ("-", only when adding, being the index past the end).
+func arrayIndex(token string, length int, adding bool) (int, error) {
+	if token == "-" && adding {
+		return length, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errPatchPath
+	}
+	if i > length || (i == length && !adding) {
+		return 0, errPatchPath
+	}
+	return i, nil
+}
+
+// pointerGet returns the value referenced by the tokens.
+func pointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			value, ok := d[token]
+			if !ok {
+				return nil, errPatchPath
+			}
+			doc = value
+		case []interface{}:
+			i, err := arrayIndex(token, len(d), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = d[i]
+		default:
+			return nil, errPatchPath
+		}
+	}
+	return doc, nil
+}
+
+// pointerPut adds (inserting into arrays) or replaces (in which case the
+// value must already exist) the value referenced by the tokens, returning
+// the updated document.
+func pointerPut(doc interface{}, tokens []string, value interface{}, adding bool) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[token]
+		if !ok && !(last && adding) {
+			return nil, errPatchPath
+		}
+		if !last {
+			var err error
+			if value, err = pointerPut(child, tokens[1:], value, adding); err != nil {
+				return nil, err
+			}
+		}
+		d[token] = value
+		return d, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(d), last && adding)
+		if err != nil {
+			return nil, err
+		}
+		if !last {
+			if value, err = pointerPut(d[i], tokens[1:], value, adding); err != nil {
+				return nil, err
+			}
+		} else if adding {
+			d = append(d[:i], append([]interface{}{value}, d[i:]...)...)
+			return d, nil
+		}
+		d[i] = value
+		return d, nil
+	}
+	return nil, errPatchPath
+}
+
+// pointerRemove removes the value referenced by the tokens, returning the
+// updated document.
+func pointerRemove(doc interface{}, tokens []string) (interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, errPatchRemoveRoot
+	}
+	token, last := tokens[0], len(tokens) == 1
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		child, ok := d[token]
+		if !ok {
+			return nil, errPatchPath
+		}
+		if last {
+			delete(d, token)
+			return d, nil
+		}
+		child, err := pointerRemove(child, tokens[1:])
+		if err != nil {
+			return nil, err
+		}
+		d[token] = child
+		return d, nil
+	case []interface{}:
+		i, err := arrayIndex(token, len(d), false)
+		if err != nil {
+			return nil, err
+		}
+		if last {
+			return append(d[:i], d[i+1:]...), nil
+		}
+		if d[i], err = pointerRemove(d[i], tokens[1:]); err != nil {
+			return nil, err
+		}
+		return d, nil
+	}
+	return nil, errPatchPath
+}
+
+// deepCopy returns a copy of a (decoded JSON) value which shares nothing with it.
+func deepCopy(value interface{}) interface{} {
+	var copied interface{}
+	b, _ := json.Marshal(value)
+	json.Unmarshal(b, &copied)
+	return copied
+}
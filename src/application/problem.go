@@ -0,0 +1,112 @@
+package application
+
+import (
+	// native packages
+	"encoding/json"
+	"log"
+	"net/http"
+
+	// local packages
+	"recipes"
+)
+
+// problemContentType is the media type of error responses (RFC 7807).
+const problemContentType = "application/problem+json"
+
+// The types of the problems reported for storage errors and invalid
+// payloads (relative to the API). Every other problem is of the type
+// "about:blank", and is simply described by its status.
+const (
+	notFoundProblem         = "/problems/not-found"
+	duplicateProblem        = "/problems/duplicate"
+	outOfRangeProblem       = "/problems/out-of-range"
+	invalidReferenceProblem = "/problems/invalid-reference"
+	modifiedProblem         = "/problems/modified"
+	validationProblem       = "/problems/validation"
+	forbiddenProblem        = "/problems/forbidden"
+)
+
+// The Problem entity is used to marshall an error response as a problem
+// detail (RFC 7807). Errors lists the fields of an invalid payload, and
+// Error repeats the detail as error responses have always carried it.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// The FieldError entity is used to marshall why a field of a payload is
+// invalid. Field is its JSON name.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// storeProblems are the problems reported for the errors returned by the store.
+var storeProblems = map[error]Problem{
+	recipes.ErrRecipeNotFound:     {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Recipe not found"},
+	recipes.ErrIngredientNotFound: {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Ingredient not found"},
+	recipes.ErrStepNotFound:       {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Step not found"},
+	recipes.ErrTermNotFound:       {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Term not found"},
+	recipes.ErrRevisionNotFound:   {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Revision not found"},
+	recipes.ErrRatingNotFound:     {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Rating not found"},
+	recipes.ErrReviewNotFound:     {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "Review not found"},
+	recipes.ErrUserNotFound:       {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "User not found"},
+	recipes.ErrAPIKeyNotFound:     {Type: notFoundProblem, Title: "Not found", Status: http.StatusNotFound, Detail: "API key not found"},
+	recipes.ErrDuplicateRecipe: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "recipe name already exists"},
+	recipes.ErrDuplicateTerm: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "term already exists"},
+	recipes.ErrDuplicateUser: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "username already exists"},
+	recipes.ErrDuplicateStep: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "step position already in use"},
+	recipes.ErrDuplicateAPIKey: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "API key already exists"},
+	recipes.ErrUniqueViolation: {Type: duplicateProblem, Title: "Already exists", Status: http.StatusConflict,
+		Detail: "A value is already in use"},
+	recipes.ErrCheckViolation: {Type: outOfRangeProblem, Title: "Value out of range", Status: http.StatusUnprocessableEntity,
+		Detail: "A value is out of range"},
+	recipes.ErrReferenceViolation: {Type: invalidReferenceProblem, Title: "Invalid reference", Status: http.StatusUnprocessableEntity,
+		Detail: "A value refers to something which does not exist"},
+	recipes.ErrVersionConflict: {Type: modifiedProblem, Title: "Modified", Status: http.StatusPreconditionFailed,
+		Detail: "Recipe has been modified (If-Match does not match its ETag)"},
+	recipes.ErrInvalidStepOrder: {Type: "about:blank", Title: http.StatusText(http.StatusBadRequest), Status: http.StatusBadRequest,
+		Detail: recipes.ErrInvalidStepOrder.Error()},
+}
+
+// respondWithProblem responds with a problem detail (the status of which is
+// the response code).
+func respondWithProblem(w http.ResponseWriter, p Problem) {
+	p.Error = p.Detail
+	response, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	w.Write(response)
+}
+
+// respondWithStoreError maps an error returned by the store to a problem.
+// Any other error is only logged, so internal details (such as SQL) never
+// reach the client.
+func respondWithStoreError(w http.ResponseWriter, err error) {
+	if p, ok := storeProblems[err]; ok {
+		respondWithProblem(w, p)
+		return
+	}
+	log.Printf("Internal error: %v", err)
+	respondWithError(w, http.StatusInternalServerError, "An internal error occurred")
+}
+
+// respondWithValidationErrors responds that the fields of a payload are invalid.
+func respondWithValidationErrors(w http.ResponseWriter, errs []FieldError) {
+	respondWithProblem(w, Problem{
+		Type:   validationProblem,
+		Title:  "Validation failed",
+		Status: http.StatusUnprocessableEntity,
+		Detail: "Invalid request payload (see errors)",
+		Errors: errs,
+	})
+}
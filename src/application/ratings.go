@@ -0,0 +1,114 @@
+package application
+
+import (
+	// native packages
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// The rating entity is used to unmarshall a rating (the rater is whoever
+// makes the request).
+type rating struct {
+	Rating int `json:"rating" validate:"min=1,max=5"`
+}
+
+// clientFingerprint identifies an anonymous client, by its address and user
+// agent (which are hashed, so are not stored).
+func clientFingerprint(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	hash := sha256.Sum256([]byte(host + "\n" + req.UserAgent()))
+	return hex.EncodeToString(hash[:])
+}
+
+// requestRating returns the rating of the recipe (by the ID in the path) by
+// whoever makes the request: the user, or else the anonymous client.
+func requestRating(w http.ResponseWriter, req *http.Request, ps httprouter.Params) (recipes.RecipeRating, bool) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return recipes.RecipeRating{}, false
+	}
+	rr := recipes.RecipeRating{RecipeID: recipeID, Username: requestUser(req)}
+	if rr.Username != "" {
+		rr.Rater = "user:" + rr.Username
+	} else {
+		rr.Rater = "client:" + clientFingerprint(req)
+	}
+	return rr, true
+}
+
+// getRatingsEndpoint lists every rating of a recipe, oldest first.
+func (a *App) getRatingsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	ratings, err := a.Store.GetRecipeRatings(recipeID)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, ratings)
+}
+
+// getRatingEndpoint returns the requester's own rating of a recipe.
+func (a *App) getRatingEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rr, ok := requestRating(w, req, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.GetRecipeRating(&rr); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rr)
+}
+
+// setRatingEndpoint rates a recipe, replacing any rating the requester has
+// already given it (so each user, or anonymous client, counts only once).
+func (a *App) setRatingEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rr, ok := requestRating(w, req, ps)
+	if !ok {
+		return
+	}
+	var payload rating
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	rr.Rating = payload.Rating
+	if err := a.Store.SetRecipeRating(&rr); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	if rr.UpdatedAt.Equal(rr.CreatedAt) {
+		respondWithJSON(w, http.StatusCreated, rr)
+	} else {
+		respondWithJSON(w, http.StatusOK, rr)
+	}
+}
+
+// deleteRatingEndpoint withdraws the requester's own rating of a recipe.
+func (a *App) deleteRatingEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rr, ok := requestRating(w, req, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.DeleteRecipeRating(&rr); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
@@ -0,0 +1,220 @@
+package application
+
+import (
+	// native packages
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// The reviewPayload entity is used to unmarshall a review (the author is
+// whoever makes the request).
+type reviewPayload struct {
+	Rating int    `json:"rating" validate:"min=1,max=5"`
+	Text   string `json:"text" validate:"required,max=5000"`
+}
+
+// reviewList is the envelope in which a page of reviews is returned.
+type reviewList struct {
+	Reviews []recipes.Review `json:"reviews"`
+	Total   int              `json:"total"`
+}
+
+// reviewParams parses the recipe ID and review ID from the path.
+func reviewParams(w http.ResponseWriter, ps httprouter.Params) (recipes.Review, bool) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return recipes.Review{}, false
+	}
+	id, err := strconv.Atoi(ps.ByName("review_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid review ID")
+		return recipes.Review{}, false
+	}
+	return recipes.Review{ID: id, RecipeID: recipeID}, true
+}
+
+// isModerator reports whether the user making a request may see hidden
+// reviews (as editors and admins may).
+func isModerator(req *http.Request) bool {
+	return hasRole(requestRole(req), recipes.Editor)
+}
+
+// visibleReview populates the specified review, responding that it is not
+// found (and returning false) if it does not exist or is hidden from the
+// user making the request.
+func (a *App) visibleReview(w http.ResponseWriter, req *http.Request, rv *recipes.Review) bool {
+	err := a.Store.GetReview(rv)
+	if err == nil && rv.Hidden && !isModerator(req) {
+		err = recipes.ErrReviewNotFound
+	}
+	if err != nil {
+		respondWithStoreError(w, err)
+		return false
+	}
+	return true
+}
+
+// getReviewsEndpoint returns a page of the reviews of a recipe, newest first,
+// by start and limit. The total number of reviews is also returned in the
+// X-Total-Count header, and the neighbouring pages are linked (RFC 5988).
+func (a *App) getReviewsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	parseForm(req)
+	start, ok := optionalInt(w, req, "start", 0, math.MaxInt32)
+	if !ok {
+		return
+	}
+	limit, ok := optionalInt(w, req, "limit", 1, maxLimit)
+	if !ok {
+		return
+	}
+	p := recipes.Page{Count: 10}
+	if start != nil {
+		p.Start = *start
+	}
+	if limit != nil {
+		p.Count = *limit
+	}
+	page, err := a.Store.GetReviews(recipeID, p.Start, p.Count, isModerator(req))
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+
+	count := strconv.Itoa(p.Count)
+	links := []string{pageLink(req, "first", map[string]string{"start": "0", "limit": count})}
+	if p.Start > 0 {
+		prev := p.Start - p.Count
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(req, "prev", map[string]string{"start": strconv.Itoa(prev), "limit": count}))
+	}
+	if p.Start+p.Count < page.Total {
+		links = append(links, pageLink(req, "next", map[string]string{"start": strconv.Itoa(p.Start + p.Count), "limit": count}))
+	}
+	w.Header().Set("Link", strings.Join(links, ", "))
+	w.Header().Set("X-Total-Count", strconv.Itoa(page.Total))
+	respondWithJSON(w, http.StatusOK, reviewList{Reviews: page.Reviews, Total: page.Total})
+}
+
+func (a *App) getReviewEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rv, ok := reviewParams(w, ps)
+	if !ok {
+		return
+	}
+	if !a.visibleReview(w, req, &rv) {
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+func (a *App) addReviewEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	var payload reviewPayload
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	rv := recipes.Review{RecipeID: recipeID, Author: requestUser(req), Rating: payload.Rating, Text: payload.Text}
+	if err := a.Store.AddReview(&rv); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, rv)
+}
+
+// modifyReviewEndpoint changes the rating and text of a review, which only
+// its author may do.
+func (a *App) modifyReviewEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rv, ok := reviewParams(w, ps)
+	if !ok {
+		return
+	}
+	var payload reviewPayload
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	if !a.visibleReview(w, req, &rv) {
+		return
+	}
+	if rv.Author != requestUser(req) {
+		respondWithForbidden(w, "Only the author of a review may modify it")
+		return
+	}
+	rv.Rating, rv.Text = payload.Rating, payload.Text
+	if err := a.Store.UpdateReview(&rv); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// deleteReviewEndpoint removes a review, which its author (or an editor) may do.
+func (a *App) deleteReviewEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rv, ok := reviewParams(w, ps)
+	if !ok {
+		return
+	}
+	if !a.visibleReview(w, req, &rv) {
+		return
+	}
+	if rv.Author != requestUser(req) && !isModerator(req) {
+		respondWithForbidden(w, "Only the author of a review (or an editor) may delete it")
+		return
+	}
+	if err := a.Store.DeleteReview(&rv); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// flagReviewEndpoint flags a review for moderation (each user's flag counting
+// only once).
+func (a *App) flagReviewEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rv, ok := reviewParams(w, ps)
+	if !ok {
+		return
+	}
+	if !a.visibleReview(w, req, &rv) {
+		return
+	}
+	if err := a.Store.FlagReview(&rv, requestUser(req)); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rv)
+}
+
+// setReviewHiddenEndpoint returns the handle which hides (or unhides) a review.
+func (a *App) setReviewHiddenEndpoint(hidden bool) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		rv, ok := reviewParams(w, ps)
+		if !ok {
+			return
+		}
+		rv.Hidden = hidden
+		if err := a.Store.SetReviewHidden(&rv); err != nil {
+			respondWithStoreError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, rv)
+	}
+}
@@ -0,0 +1,80 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// revisionParams parses the recipe ID and revision number from the path.
+func revisionParams(w http.ResponseWriter, ps httprouter.Params) (recipes.Revision, bool) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return recipes.Revision{}, false
+	}
+	revision, err := strconv.Atoi(ps.ByName("revision"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid revision")
+		return recipes.Revision{}, false
+	}
+	return recipes.Revision{RecipeID: recipeID, Revision: revision}, true
+}
+
+func (a *App) getRevisionsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	revisions, err := a.Store.GetRevisions(recipeID)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, revisions)
+}
+
+func (a *App) getRevisionEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rev, ok := revisionParams(w, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.GetRevision(&rev); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, rev)
+}
+
+// restoreRevisionEndpoint makes a revision of a recipe the current one, which
+// is itself recorded as a new revision (so may be undone in turn).
+func (a *App) restoreRevisionEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	rev, ok := revisionParams(w, ps)
+	if !ok {
+		return
+	}
+	version, ok := a.ifMatch(w, req, rev.RecipeID)
+	if !ok {
+		return
+	}
+	if err := a.Store.GetRevision(&rev); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	r := rev.Recipe
+	r.Version = version
+	r.ModifiedBy = requestUser(req)
+	if err := a.Store.UpdateRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	w.Header().Set("ETag", recipeETag(r.Version))
+	respondWithJSON(w, http.StatusOK, r)
+}
@@ -0,0 +1,157 @@
+package application
+
+import (
+	// native packages
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	// local packages
+	"recipes"
+)
+
+// parseForm parses the query, and any form, of the request (once).
+func parseForm(req *http.Request) {
+	if req.Form == nil {
+		req.ParseMultipartForm(32 << 20)
+	}
+}
+
+// pageParams parses the start and count of a page of recipes, responding
+// with an error (and returning false) if either is not a number. Out of
+// range values are clamped, as they always have been.
+func pageParams(w http.ResponseWriter, req *http.Request) (start int, count int, ok bool) {
+	parseForm(req)
+	for _, param := range []struct {
+		name  string
+		value *int
+	}{{"start", &start}, {"count", &count}} {
+		if value := req.Form.Get(param.name); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				respondWithError(w, http.StatusBadRequest, "Invalid "+param.name)
+				return 0, 0, false
+			}
+			*param.value = n
+		}
+	}
+	if count > 10 || count < 1 {
+		count = 10
+	}
+	if start < 0 {
+		start = 0
+	}
+	return start, count, true
+}
+
+// recipeSort parses the sort order of the recipes, responding with an error
+// (and returning false) if it is invalid.
+func recipeSort(w http.ResponseWriter, req *http.Request) (recipes.RecipeSort, bool) {
+	parseForm(req)
+	value := req.Form.Get("sort")
+	if value == "" {
+		return recipes.RecipeSort{}, true
+	}
+	o, err := recipes.ParseSort(value)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest,
+			"Invalid sort (expected one of "+strings.Join(recipes.SortFields, ", ")+", optionally prefixed by '-')")
+		return o, false
+	}
+	return o, true
+}
+
+// recipeFilter parses the filters used when listing or searching recipes,
+// responding with an error (and returning false) if any of them is invalid.
+// Tags, cuisines, courses and difficulties may be repeated, in which case a
+// recipe must have all of the tags, cuisines and courses, and any one of the
+// difficulties, listed.
+func recipeFilter(w http.ResponseWriter, req *http.Request) (recipes.RecipeFilter, bool) {
+	parseForm(req)
+	f := recipes.RecipeFilter{
+		Text:     strings.TrimSpace(req.Form.Get("q")),
+		Tags:     req.Form["tag"],
+		Cuisines: req.Form["cuisine"],
+		Courses:  req.Form["course"],
+	}
+	var ok bool
+	if f.MinPrepTime, ok = optionalFloat(w, req, "min_preptime", 0, math.MaxFloat32); !ok {
+		return f, false
+	}
+	if f.MaxPrepTime, ok = optionalFloat(w, req, "max_preptime", 0, math.MaxFloat32); !ok {
+		return f, false
+	}
+	if f.MinPrepTime != nil && f.MaxPrepTime != nil && *f.MinPrepTime > *f.MaxPrepTime {
+		respondWithError(w, http.StatusBadRequest, "Invalid preptime range (min_preptime exceeds max_preptime)")
+		return f, false
+	}
+	// the original (exclusive) upper bound is still accepted
+	if f.PrepTimeBelow, ok = optionalFloat(w, req, "preptime", 0, math.MaxFloat32); !ok {
+		return f, false
+	}
+	for _, values := range req.Form["difficulty"] {
+		for _, value := range strings.Split(values, ",") {
+			difficulty, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || difficulty < 1 || difficulty > 3 {
+				respondWithError(w, http.StatusBadRequest, "Invalid difficulty (expected 1, 2 or 3)")
+				return f, false
+			}
+			f.Difficulties = append(f.Difficulties, difficulty)
+		}
+	}
+	if value := req.Form.Get("vegetarian"); value != "" {
+		vegetarian, err := strconv.ParseBool(value)
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid vegetarian (expected true or false)")
+			return f, false
+		}
+		f.Vegetarian = &vegetarian
+	}
+	minAvgRating, ok := optionalFloat(w, req, "min_avg_rating", 0, 5)
+	if !ok {
+		return f, false
+	}
+	if minAvgRating != nil {
+		f.MinAvgRating = float64(*minAvgRating)
+	}
+	minRatingCount, ok := optionalInt(w, req, "min_rating_count", 0, math.MaxInt32)
+	if !ok {
+		return f, false
+	}
+	if minRatingCount != nil {
+		f.MinRatingCount = *minRatingCount
+	}
+	return f, true
+}
+
+// optionalFloat parses an optional number (between min and max) from the
+// request, responding with an error (and returning false) if it is invalid.
+func optionalFloat(w http.ResponseWriter, req *http.Request, name string, min, max float64) (*float32, bool) {
+	value := req.Form.Get(name)
+	if value == "" {
+		return nil, true
+	}
+	f, err := strconv.ParseFloat(value, 32)
+	if err != nil || math.IsNaN(f) || f < min || f > max {
+		respondWithError(w, http.StatusBadRequest, "Invalid "+name)
+		return nil, false
+	}
+	f32 := float32(f)
+	return &f32, true
+}
+
+// optionalInt parses an optional integer (between min and max) from the
+// request, responding with an error (and returning false) if it is invalid.
+func optionalInt(w http.ResponseWriter, req *http.Request, name string, min, max int) (*int, bool) {
+	value := req.Form.Get(name)
+	if value == "" {
+		return nil, true
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < min || i > max {
+		respondWithError(w, http.StatusBadRequest, "Invalid "+name)
+		return nil, false
+	}
+	return &i, true
+}
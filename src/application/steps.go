@@ -0,0 +1,134 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// stepParams parses the recipe and step IDs from the path.
+func stepParams(w http.ResponseWriter, ps httprouter.Params) (recipes.Step, bool) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return recipes.Step{}, false
+	}
+	stepID, err := strconv.Atoi(ps.ByName("step_id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid step ID")
+		return recipes.Step{}, false
+	}
+	return recipes.Step{ID: stepID, RecipeID: recipeID}, true
+}
+
+// respondWithStepError maps step storage errors to responses.
+func respondWithStepError(w http.ResponseWriter, err error) {
+	switch err {
+	case recipes.ErrCheckViolation:
+		respondWithError(w, http.StatusBadRequest, "Invalid step (text is required, duration and timer must not be negative)")
+	default:
+		respondWithStoreError(w, err)
+	}
+}
+
+func (a *App) getStepsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	steps, err := a.Store.GetSteps(recipeID)
+	if err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, steps)
+}
+
+func (a *App) getStepEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	st, ok := stepParams(w, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.GetStep(&st); err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, st)
+}
+
+func (a *App) addStepEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	var st recipes.Step
+	if !decodePayload(w, req, &st) {
+		return
+	}
+	st.RecipeID = recipeID
+	if err := a.Store.AddStep(&st); err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusCreated, st)
+}
+
+func (a *App) modifyStepEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	ids, ok := stepParams(w, ps)
+	if !ok {
+		return
+	}
+	var st recipes.Step
+	if !decodePayload(w, req, &st) {
+		return
+	}
+	st.ID, st.RecipeID = ids.ID, ids.RecipeID
+	if err := a.Store.UpdateStep(&st); err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, st)
+}
+
+func (a *App) deleteStepEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	st, ok := stepParams(w, ps)
+	if !ok {
+		return
+	}
+	if err := a.Store.DeleteStep(&st); err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
+
+// reorderStepsEndpoint expects every step ID of the recipe, in the new order:
+//
+//	{"step_ids":[3,1,2]}
+func (a *App) reorderStepsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	var order struct {
+		StepIDs []int `json:"step_ids"`
+	}
+	if !decodePayload(w, req, &order) {
+		return
+	}
+	steps, err := a.Store.ReorderSteps(recipeID, order.StepIDs)
+	if err != nil {
+		respondWithStepError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, steps)
+}
@@ -0,0 +1,40 @@
+package application
+
+import (
+	// native packages
+	"fmt"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/jmoiron/sqlx"
+	// Standard SQL Overrides
+	_ "github.com/lib/pq"
+)
+
+// OpenStore connects to the storage back-end selected by dbDriver: "postgres"
+// (the default), "sqlite3" (where dbName is the database file and the other
+// database parameters are ignored) or "memory" (where all of the database
+// parameters are ignored).
+func OpenStore(dbDriver, dbHost, dbUser, dbPassword, dbName string) (recipes.Store, error) {
+
+	switch dbDriver {
+	case "", recipes.Postgres:
+		connectionString := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", dbUser, dbPassword, dbHost, dbName)
+		db, err := sqlx.Open(recipes.Postgres, connectionString)
+		if err != nil {
+			return nil, err
+		}
+		return recipes.NewPostgresStore(db), nil
+	case recipes.SQLite:
+		db, err := sqlx.Open(recipes.SQLiteDriver, recipes.SQLiteDataSource(dbName))
+		if err != nil {
+			return nil, err
+		}
+		return recipes.NewSQLiteStore(db), nil
+	case "memory":
+		return recipes.NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("unknown database driver: %s", dbDriver)
+}
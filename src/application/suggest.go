@@ -0,0 +1,39 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strings"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// maxSuggestions is the most recipe names which may be suggested at once.
+const maxSuggestions = 20
+
+// suggestRecipesEndpoint suggests the recipes whose names most closely match
+// a prefix (as typed so far, so possibly misspelt) for autocompletion. It is
+// served at /v1/recipes/suggest by getRecipeEndpoint.
+func (a *App) suggestRecipesEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	parseForm(req)
+	prefix := strings.TrimSpace(req.Form.Get("prefix"))
+	if prefix == "" {
+		respondWithError(w, http.StatusBadRequest, "Invalid prefix (must not be empty)")
+		return
+	}
+	limit, ok := optionalInt(w, req, "limit", 1, maxSuggestions)
+	if !ok {
+		return
+	}
+	count := 10
+	if limit != nil {
+		count = *limit
+	}
+	suggestions, err := a.Store.SuggestRecipes(prefix, count)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, suggestions)
+}
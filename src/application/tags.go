@@ -0,0 +1,70 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// respondWithTagError maps tag and taxonomy storage errors to responses.
+func respondWithTagError(w http.ResponseWriter, err error) {
+	switch err {
+	case recipes.ErrCheckViolation:
+		respondWithError(w, http.StatusBadRequest, "Invalid name (must not be empty or longer than 50 characters)")
+	default:
+		respondWithStoreError(w, err)
+	}
+}
+
+func (a *App) getTagsEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	tags, err := a.Store.GetTags()
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+func (a *App) getRecipeTagsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	tags, err := a.Store.GetRecipeTags(recipeID)
+	if err != nil {
+		respondWithTagError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
+
+func (a *App) setRecipeTagsEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	recipeID, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	var payload struct {
+		Tags []string `json:"tags"`
+	}
+	if !decodePayload(w, req, &payload) {
+		return
+	}
+	tags, err := a.Store.SetRecipeTags(recipeID, payload.Tags)
+	if err == recipes.ErrCheckViolation {
+		respondWithValidationErrors(w, []FieldError{{Field: "tags", Message: "must not be empty or longer than 50 characters"}})
+		return
+	}
+	if err != nil {
+		respondWithTagError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, tags)
+}
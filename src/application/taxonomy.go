@@ -0,0 +1,112 @@
+package application
+
+import (
+	// native packages
+	"net/http"
+	"strconv"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// taxonomyCollections names the resource for each taxonomy (both at the top
+// level and beneath each recipe).
+var taxonomyCollections = map[string]string{
+	recipes.Cuisine: "cuisines",
+	recipes.Course:  "courses",
+}
+
+func (a *App) getTermsEndpoint(taxonomy string) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		terms, err := a.Store.GetTerms(taxonomy)
+		if err != nil {
+			respondWithStoreError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, terms)
+	}
+}
+
+func (a *App) addTermEndpoint(taxonomy string) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+		var t recipes.Term
+		if !decodePayload(w, req, &t) {
+			return
+		}
+		t.Taxonomy = taxonomy
+		if err := a.Store.AddTerm(&t); err != nil {
+			respondWithTagError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusCreated, t)
+	}
+}
+
+func (a *App) deleteTermEndpoint(taxonomy string) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		termID, err := strconv.Atoi(ps.ByName("term_id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid term ID")
+			return
+		}
+		t := recipes.Term{ID: termID, Taxonomy: taxonomy}
+		if err := a.Store.DeleteTerm(&t); err != nil {
+			respondWithTagError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+	}
+}
+
+func (a *App) getRecipeTermsEndpoint(taxonomy string) httprouter.Handle {
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		recipeID, err := strconv.Atoi(ps.ByName("id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+			return
+		}
+		terms, err := a.Store.GetRecipeTerms(recipeID, taxonomy)
+		if err != nil {
+			respondWithTagError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, terms)
+	}
+}
+
+func (a *App) setRecipeTermsEndpoint(taxonomy string) httprouter.Handle {
+	collection := taxonomyCollections[taxonomy]
+	return func(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+		recipeID, err := strconv.Atoi(ps.ByName("id"))
+		if err != nil {
+			respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+			return
+		}
+		var payload map[string][]string
+		if !decodePayload(w, req, &payload) {
+			return
+		}
+		// the payload is keyed by the collection, so is decoded into a map
+		for field := range payload {
+			if field != collection {
+				respondWithValidationErrors(w, []FieldError{{Field: field, Message: "is not a known field"}})
+				return
+			}
+		}
+		terms, err := a.Store.SetRecipeTerms(recipeID, taxonomy, payload[collection])
+		switch err {
+		case nil:
+		case recipes.ErrTermNotFound, recipes.ErrCheckViolation:
+			// the term is part of the payload (rather than the path)
+			respondWithValidationErrors(w, []FieldError{{Field: collection, Message: "must be listed in /v1/" + collection}})
+			return
+		default:
+			respondWithTagError(w, err)
+			return
+		}
+		respondWithJSON(w, http.StatusOK, terms)
+	}
+}
@@ -0,0 +1,117 @@
+package application
+
+import (
+	// native packages
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	// local packages
+	"recipes"
+
+	// GitHub packages
+	"github.com/julienschmidt/httprouter"
+)
+
+// DefaultTrashRetention is how long deleted recipes are kept in the trash
+// before they are purged, unless another retention period is configured.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// purgeInterval is how often the trash is purged while serving.
+const purgeInterval = time.Hour
+
+// ParseRetention parses a trash retention period, which is either a number
+// of days (such as "30d") or a duration (such as "36h"). An empty period is
+// the DefaultTrashRetention.
+func ParseRetention(period string) (time.Duration, error) {
+	if period == "" {
+		return DefaultTrashRetention, nil
+	}
+	if strings.HasSuffix(period, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(period, "d"))
+		if err == nil && days >= 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	} else if retention, err := time.ParseDuration(period); err == nil && retention >= 0 {
+		return retention, nil
+	}
+	return 0, fmt.Errorf("invalid trash retention %q (expected a number of days, such as 30d, or a duration, such as 36h)", period)
+}
+
+// Purge permanently deletes the recipes which have been in the trash for
+// longer than the retention period, reporting how many to out.
+func Purge(out io.Writer, store recipes.Store, retention time.Duration) error {
+	purged, err := store.PurgeRecipes(time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Purged %d recipe(s) deleted more than %v ago\n", purged, retention)
+	return nil
+}
+
+// PurgePeriodically purges the trash of the app's store every purgeInterval
+// (starting straight away), for as long as the app runs.
+func (a *App) PurgePeriodically(retention time.Duration) {
+	for {
+		purged, err := a.Store.PurgeRecipes(time.Now().Add(-retention))
+		if err != nil {
+			log.Printf("Purging the trash failed: %v", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d recipe(s) from the trash", purged)
+		}
+		time.Sleep(purgeInterval)
+	}
+}
+
+func (a *App) getTrashEndpoint(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	start, count, ok := pageParams(w, req)
+	if !ok {
+		return
+	}
+	deleted, err := a.Store.GetDeletedRecipes(start, count)
+	if err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, deleted)
+}
+
+// restoreRecipeEndpoint takes a recipe out of the trash, along with
+// everything belonging to it.
+func (a *App) restoreRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	r := recipes.Recipe{ID: id, ModifiedBy: requestUser(req)}
+	if err := a.Store.RestoreRecipe(&r); err != nil {
+		respondWithStoreError(w, err)
+		return
+	}
+	w.Header().Set("ETag", recipeETag(r.Version))
+	respondWithJSON(w, http.StatusOK, r)
+}
+
+// purgeRecipeEndpoint permanently deletes a recipe in the trash, along with
+// everything belonging to it.
+func (a *App) purgeRecipeEndpoint(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := strconv.Atoi(ps.ByName("id"))
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid recipe ID")
+		return
+	}
+	if err := a.Store.PurgeRecipe(&recipes.Recipe{ID: id}); err != nil {
+		if err == recipes.ErrRecipeNotFound {
+			respondWithError(w, http.StatusNotFound, "Recipe not found in the trash")
+		} else {
+			respondWithStoreError(w, err)
+		}
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]string{"result": "success"})
+}
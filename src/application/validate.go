@@ -0,0 +1,157 @@
+package application
+
+import (
+	// native packages
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// decodePayload decodes the JSON payload of a request into v, responding
+// with an error (and returning false) if the payload is missing or is not
+// JSON, has fields which v does not, or breaks the rules of v's validate
+// tags. The payload is checked before anything is stored.
+func decodePayload(w http.ResponseWriter, req *http.Request, v interface{}) bool {
+	if req.Body == nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload (missing)")
+		return false
+	}
+	defer req.Body.Close()
+	decoder := json.NewDecoder(req.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		if errs := decodeErrors(err); errs != nil {
+			respondWithValidationErrors(w, errs)
+		} else {
+			respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		}
+		return false
+	}
+	if errs := validate(v); errs != nil {
+		respondWithValidationErrors(w, errs)
+		return false
+	}
+	return true
+}
+
+// decodeErrors returns the field errors of a JSON decoding error, which are
+// an unknown field or a field of the wrong type. It returns nil for any other
+// error (such as a syntax error).
+func decodeErrors(err error) []FieldError {
+	if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+		return []FieldError{{Field: typeErr.Field, Message: "must be " + jsonType(typeErr.Type)}}
+	}
+	// encoding/json has no type for unknown fields, only this message
+	const unknownField = "json: unknown field "
+	if message := err.Error(); strings.HasPrefix(message, unknownField) {
+		field, unquoteErr := strconv.Unquote(strings.TrimPrefix(message, unknownField))
+		if unquoteErr == nil {
+			return []FieldError{{Field: field, Message: "is not a known field"}}
+		}
+	}
+	return nil
+}
+
+// jsonType describes the JSON values which decode into a Go type.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a whole number"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
+}
+
+// validate checks the fields of a struct (or a pointer to one) against the
+// comma separated rules of their validate tags, returning an error for each
+// field which breaks them (named as in JSON). The rules are:
+//
+//	required  a string must not be blank
+//	min=n     a number must not be less than n, nor a string shorter than n characters
+//	max=n     a number must not be more than n, nor a string longer than n characters
+//	oneof=a b a string must be one of the (space separated) values
+func validate(v interface{}) []FieldError {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs []FieldError
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		rules := field.Tag.Get("validate")
+		if rules == "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		for _, rule := range strings.Split(rules, ",") {
+			if message := checkRule(value.Field(i), rule); message != "" {
+				errs = append(errs, FieldError{Field: name, Message: message})
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// checkRule checks a value against a validate rule, returning why the value
+// breaks it (or "" if it does not).
+func checkRule(value reflect.Value, rule string) string {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	if name == "required" {
+		if value.Kind() == reflect.String && strings.TrimSpace(value.String()) == "" {
+			return "is required"
+		}
+		return ""
+	}
+	if name == "oneof" {
+		for _, allowed := range strings.Fields(arg) {
+			if value.String() == allowed {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(strings.Fields(arg), ", ")
+	}
+	bound, err := strconv.ParseFloat(arg, 64)
+	if err != nil || (name != "min" && name != "max") {
+		panic("invalid validate rule: " + rule)
+	}
+
+	var n float64
+	unit := ""
+	switch value.Kind() {
+	case reflect.String:
+		n, unit = float64(utf8.RuneCountInString(value.String())), " characters long"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		n = value.Float()
+	default:
+		return ""
+	}
+	if name == "min" && n < bound {
+		return "must be at least " + arg + unit
+	}
+	if name == "max" && n > bound {
+		return "must be at most " + arg + unit
+	}
+	return ""
+}
@@ -1,17 +1,70 @@
 package main
 
-import "os"
+import (
+	"log"
+	"os"
+)
 
 import "application"
 
 func main() {
-	app := application.App{}
+	dbName := os.Getenv("POSTGRES_DB")
+	if os.Getenv("DB_DRIVER") == "sqlite3" {
+		dbName = os.Getenv("SQLITE_DB")
+	}
+
+	retention, err := application.ParseRetention(os.Getenv("TRASH_RETENTION"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// restful_recipes migrate up|down|status
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		command := ""
+		if len(os.Args) > 2 {
+			command = os.Args[2]
+		}
+		store, err := application.OpenStore(
+			os.Getenv("DB_DRIVER"),
+			os.Getenv("POSTGRES_HOST"),
+			os.Getenv("POSTGRES_USER"),
+			os.Getenv("POSTGRES_PASSWORD"),
+			dbName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := application.Migrate(os.Stdout, store, command); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// restful_recipes purge
+	if len(os.Args) > 1 && os.Args[1] == "purge" {
+		store, err := application.OpenStore(
+			os.Getenv("DB_DRIVER"),
+			os.Getenv("POSTGRES_HOST"),
+			os.Getenv("POSTGRES_USER"),
+			os.Getenv("POSTGRES_PASSWORD"),
+			dbName)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := application.Purge(os.Stdout, store, retention); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	app := application.App{TokenSecret: []byte(os.Getenv("JWT_SECRET"))}
 	app.Initialize(
+		os.Getenv("DB_DRIVER"),
 		os.Getenv("POSTGRES_HOST"),
 		os.Getenv("POSTGRES_USER"),
 		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
+		dbName,
 		os.Getenv("AUTH_USER"),
 		os.Getenv("AUTH_PASSWORD"))
+	go app.PurgePeriodically(retention)
 	app.Run(os.Getenv("PORT"))
 }
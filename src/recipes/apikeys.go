@@ -0,0 +1,137 @@
+package recipes
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// apiKeyColumns are the columns selected for each API key, in the order
+// scanned by scanAPIKey (the hash of the key is never selected).
+const apiKeyColumns = "key_id, name, username, scope, prefix, created_at, expires_at, last_used_at"
+
+// checkAPIKey enforces the api_keys table constraints, storing its expiry
+// as times are stored.
+func checkAPIKey(k *APIKey) error {
+	if k.Name == "" || (k.Scope != ReadScope && k.Scope != WriteScope) {
+		return ErrCheckViolation
+	}
+	if k.ExpiresAt != nil {
+		expiresAt := k.ExpiresAt.UTC().Truncate(time.Microsecond)
+		k.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// scanAPIKey scans the apiKeyColumns of a row into an API key.
+func scanAPIKey(scan func(dest ...interface{}) error, k *APIKey) error {
+	return scan(&k.ID, &k.Name, &k.Username, &k.Scope, &k.Prefix, &k.CreatedAt, &k.ExpiresAt, &k.LastUsedAt)
+}
+
+// GetAPIKeys returns every API key, in the order they were created.
+func (s *SQLStore) GetAPIKeys() ([]APIKey, error) {
+	rows, err := s.DB.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY key_id")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := scanAPIKey(rows.Scan, &k); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+// CreateAPIKey is used to create a single API key (from its hash).
+func (s *SQLStore) CreateAPIKey(k *APIKey) error {
+	if err := checkAPIKey(k); err != nil {
+		return err
+	}
+	k.CreatedAt = now()
+	return s.mapDuplicateError(s.DB.QueryRow(
+		"INSERT INTO api_keys(name, username, scope, prefix, key_hash, created_at, expires_at) "+
+			"VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING key_id",
+		k.Name, k.Username, k.Scope, k.Prefix, k.KeyHash, k.CreatedAt, k.ExpiresAt).Scan(&k.ID), ErrDuplicateAPIKey)
+}
+
+// UseAPIKey populates the specified API key (by hash), recording that it
+// has been used.
+func (s *SQLStore) UseAPIKey(k *APIKey) error {
+	err := scanAPIKey(s.DB.QueryRow("UPDATE api_keys SET last_used_at=$1 WHERE key_hash=$2 RETURNING "+apiKeyColumns,
+		now(), k.KeyHash).Scan, k)
+	if err == sql.ErrNoRows {
+		return ErrAPIKeyNotFound
+	}
+	return err
+}
+
+// DeleteAPIKey is used to revoke a specific API key.
+func (s *SQLStore) DeleteAPIKey(k *APIKey) error {
+	res, err := s.DB.Exec("DELETE FROM api_keys WHERE key_id=$1", k.ID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res, ErrAPIKeyNotFound)
+}
+
+// GetAPIKeys returns every API key, in the order they were created.
+func (s *MemoryStore) GetAPIKeys() ([]APIKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := []APIKey{}
+	for _, k := range s.apiKeys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys, nil
+}
+
+// CreateAPIKey is used to create a single API key (from its hash).
+func (s *MemoryStore) CreateAPIKey(k *APIKey) error {
+	if err := checkAPIKey(k); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k.ID = s.nextAPIKeyID
+	s.nextAPIKeyID++
+	k.CreatedAt = now()
+	stored := *k
+	stored.Key = ""
+	s.apiKeys[k.ID] = stored
+	return nil
+}
+
+// UseAPIKey populates the specified API key (by hash), recording that it
+// has been used.
+func (s *MemoryStore) UseAPIKey(k *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, existing := range s.apiKeys {
+		if existing.KeyHash == k.KeyHash {
+			usedAt := now()
+			existing.LastUsedAt = &usedAt
+			s.apiKeys[id] = existing
+			*k = existing
+			return nil
+		}
+	}
+	return ErrAPIKeyNotFound
+}
+
+// DeleteAPIKey is used to revoke a specific API key.
+func (s *MemoryStore) DeleteAPIKey(k *APIKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.apiKeys[k.ID]; !ok {
+		return ErrAPIKeyNotFound
+	}
+	delete(s.apiKeys, k.ID)
+	return nil
+}
@@ -0,0 +1,209 @@
+package recipes
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxTagFacets is the most tags counted in the facets (the most used ones).
+const maxTagFacets = 20
+
+// A FacetRange is a bucket of a numeric facet, from Min (inclusive) up to
+// Max (exclusive), or without an upper bound if Max is 0. The bounds
+// correspond to the min_preptime and preptime (or min_avg_rating) filters.
+type FacetRange struct {
+	Value    string
+	Min, Max float64
+}
+
+// PrepTimeRanges are the buckets of the preptime facet, in minutes.
+var PrepTimeRanges = []FacetRange{
+	{"under 15", 0, 15}, {"15-30", 15, 30}, {"30-60", 30, 60}, {"60-120", 60, 120}, {"120+", 120, 0},
+}
+
+// RatingRanges are the buckets of the (average) rating facet.
+var RatingRanges = []FacetRange{
+	{"unrated", 0, 1}, {"1-2", 1, 2}, {"2-3", 2, 3}, {"3-4", 3, 4}, {"4-5", 4, 0},
+}
+
+// contains reports whether the value lies within the range.
+func (r FacetRange) contains(v float64) bool {
+	return v >= r.Min && (r.Max == 0 || v < r.Max)
+}
+
+// condition returns the SQL condition selecting values of the column within
+// the range (whose bounds are constants, so are not bound).
+func (r FacetRange) condition(column string) string {
+	cond := column + " >= " + strconv.FormatFloat(r.Min, 'f', -1, 64)
+	if r.Max != 0 {
+		cond += " AND " + column + " < " + strconv.FormatFloat(r.Max, 'f', -1, 64)
+	}
+	return cond
+}
+
+// The FacetCount entity is used to marshall/unmarshall JSON. It is the
+// number of recipes with a value of a facet, or within a range of values.
+type FacetCount struct {
+	Value string   `json:"value"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// The Facets entity is used to marshall/unmarshall JSON. It breaks down the
+// recipes which match a filter by difficulty, whether they are vegetarian,
+// preptime, tag (the most used ones) and average rating. Every difficulty,
+// vegetarian value and range is counted, even if no recipe has it.
+type Facets struct {
+	Difficulty []FacetCount `json:"difficulty"`
+	Vegetarian []FacetCount `json:"vegetarian"`
+	PrepTime   []FacetCount `json:"preptime"`
+	Tags       []FacetCount `json:"tags"`
+	Rating     []FacetCount `json:"rating"`
+}
+
+// The values of the difficulty and vegetarian facets, which are always counted.
+var (
+	facetDifficulties = []int{1, 2, 3}
+	facetVegetarian   = []bool{true, false}
+)
+
+// newFacets returns the facets with every value counted as 0 (and no tags).
+func newFacets() Facets {
+	f := Facets{Tags: []FacetCount{}}
+	for _, d := range facetDifficulties {
+		f.Difficulty = append(f.Difficulty, FacetCount{Value: strconv.Itoa(d)})
+	}
+	for _, v := range facetVegetarian {
+		f.Vegetarian = append(f.Vegetarian, FacetCount{Value: strconv.FormatBool(v)})
+	}
+	f.PrepTime = rangeCounts(PrepTimeRanges)
+	f.Rating = rangeCounts(RatingRanges)
+	return f
+}
+
+// rangeCounts returns a count of 0 for each of the ranges.
+func rangeCounts(ranges []FacetRange) []FacetCount {
+	counts := []FacetCount{}
+	for _, r := range ranges {
+		min := r.Min
+		c := FacetCount{Value: r.Value, Min: &min}
+		if r.Max != 0 {
+			max := r.Max
+			c.Max = &max
+		}
+		counts = append(counts, c)
+	}
+	return counts
+}
+
+// counts returns pointers to every count (besides the tags), in the order
+// of the columns selected by facetColumns.
+func (f *Facets) counts() []*int {
+	counts := []*int{}
+	for _, facet := range [][]FacetCount{f.Difficulty, f.Vegetarian, f.PrepTime, f.Rating} {
+		for i := range facet {
+			counts = append(counts, &facet[i].Count)
+		}
+	}
+	return counts
+}
+
+// facetColumns returns the SQL expressions counting the recipes with each
+// value (besides the tags), in the order of Facets.counts.
+func facetColumns() string {
+	conds := []string{}
+	for _, d := range facetDifficulties {
+		conds = append(conds, "difficulty = "+strconv.Itoa(d))
+	}
+	conds = append(conds, "vegetarian", "NOT vegetarian")
+	for _, r := range PrepTimeRanges {
+		conds = append(conds, r.condition("preptime"))
+	}
+	for _, r := range RatingRanges {
+		conds = append(conds, r.condition("avg_rating"))
+	}
+	columns := []string{}
+	for _, cond := range conds {
+		columns = append(columns, "COALESCE(SUM(CASE WHEN "+cond+" THEN 1 ELSE 0 END), 0)")
+	}
+	return strings.Join(columns, ", ")
+}
+
+// GetRecipeFacets counts the recipes which match the filter by each facet.
+func (s *SQLStore) GetRecipeFacets(f RecipeFilter) (Facets, error) {
+	facets := newFacets()
+	counts := []interface{}{}
+	for _, c := range facets.counts() {
+		counts = append(counts, c)
+	}
+	q := &recipeQuery{}
+	if err := s.DB.QueryRow("SELECT "+facetColumns()+s.matching(f, q, nil), q.args...).Scan(counts...); err != nil {
+		return facets, err
+	}
+
+	q = &recipeQuery{}
+	rows, err := s.DB.Query(
+		"SELECT t.name, COUNT(*) FROM tags t JOIN recipe_tags rt ON rt.tag_id = t.tag_id "+
+			"WHERE rt.recipe_id IN (SELECT id"+s.matching(f, q, nil)+") "+
+			"GROUP BY t.name ORDER BY COUNT(*) DESC, t.name LIMIT "+strconv.Itoa(maxTagFacets),
+		q.args...)
+	if err != nil {
+		return facets, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var c FacetCount
+		if err := rows.Scan(&c.Value, &c.Count); err != nil {
+			return facets, err
+		}
+		facets.Tags = append(facets.Tags, c)
+	}
+
+	return facets, rows.Err()
+}
+
+// GetRecipeFacets counts the recipes which match the filter by each facet.
+func (s *MemoryStore) GetRecipeFacets(f RecipeFilter) (Facets, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	facets := newFacets()
+	tags := map[string]int{}
+	for _, rr := range s.query(f, RecipeSort{}) {
+		facets.Difficulty[rr.Difficulty-1].Count++
+		if rr.Vegetarian {
+			facets.Vegetarian[0].Count++
+		} else {
+			facets.Vegetarian[1].Count++
+		}
+		for i, r := range PrepTimeRanges {
+			if r.contains(float64(rr.PrepTime)) {
+				facets.PrepTime[i].Count++
+			}
+		}
+		for i, r := range RatingRanges {
+			if r.contains(rr.AvgRating) {
+				facets.Rating[i].Count++
+			}
+		}
+		for _, tag := range s.tags[rr.ID] {
+			tags[tag]++
+		}
+	}
+
+	for name, n := range tags {
+		facets.Tags = append(facets.Tags, FacetCount{Value: name, Count: n})
+	}
+	sort.Slice(facets.Tags, func(i, j int) bool {
+		if facets.Tags[i].Count != facets.Tags[j].Count {
+			return facets.Tags[i].Count > facets.Tags[j].Count
+		}
+		return facets.Tags[i].Value < facets.Tags[j].Value
+	})
+	if len(facets.Tags) > maxTagFacets {
+		facets.Tags = facets.Tags[:maxTagFacets]
+	}
+	return facets, nil
+}
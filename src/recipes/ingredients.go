@@ -0,0 +1,208 @@
+package recipes
+
+import "database/sql"
+
+// recipeExists reports whether the specified recipe exists (and is not in the trash).
+func (s *SQLStore) recipeExists(recipeID int) (bool, error) {
+	var exists bool
+	err := s.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL)", recipeID).Scan(&exists)
+	return exists, err
+}
+
+// GetIngredients returns the ingredients of a specific recipe.
+func (s *SQLStore) GetIngredients(recipeID int) ([]Ingredient, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+
+	rows, err := s.DB.Query(
+		"SELECT ingredient_id, recipe_id, quantity, unit, item, note FROM ingredients WHERE recipe_id=$1 ORDER BY ingredient_id",
+		recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	ingredients := []Ingredient{}
+	for rows.Next() {
+		var i Ingredient
+		if err := rows.Scan(&i.ID, &i.RecipeID, &i.Quantity, &i.Unit, &i.Item, &i.Note); err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, i)
+	}
+
+	return ingredients, rows.Err()
+}
+
+// GetIngredient returns a single specified ingredient.
+func (s *SQLStore) GetIngredient(i *Ingredient) error {
+	err := s.DB.QueryRow(
+		"SELECT quantity, unit, item, note FROM ingredients WHERE ingredient_id=$1 AND recipe_id=$2 AND "+notInTrash,
+		i.ID, i.RecipeID).Scan(&i.Quantity, &i.Unit, &i.Item, &i.Note)
+	if err == sql.ErrNoRows {
+		return ErrIngredientNotFound
+	}
+	return err
+}
+
+// AddIngredient adds an ingredient to a specific recipe.
+func (s *SQLStore) AddIngredient(i *Ingredient) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRow(
+		"INSERT INTO ingredients(recipe_id, quantity, unit, item, note) "+
+			"SELECT $1, $2, $3, $4, $5 WHERE EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL) "+
+			"RETURNING ingredient_id",
+		i.RecipeID, i.Quantity, i.Unit, i.Item, i.Note).Scan(&i.ID)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	if err != nil {
+		return s.mapError(err)
+	}
+	if err := touchRecipe(tx, i.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateIngredient is used to modify a specific ingredient.
+func (s *SQLStore) UpdateIngredient(i *Ingredient) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		"UPDATE ingredients SET quantity=$1, unit=$2, item=$3, note=$4 WHERE ingredient_id=$5 AND recipe_id=$6 AND "+notInTrash,
+		i.Quantity, i.Unit, i.Item, i.Note, i.ID, i.RecipeID)
+	if err != nil {
+		return s.mapError(err)
+	}
+	if err := checkRowsAffected(res, ErrIngredientNotFound); err != nil {
+		return err
+	}
+	if err := touchRecipe(tx, i.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteIngredient is used to remove a specific ingredient.
+func (s *SQLStore) DeleteIngredient(i *Ingredient) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec("DELETE FROM ingredients WHERE ingredient_id=$1 AND recipe_id=$2 AND "+notInTrash, i.ID, i.RecipeID)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(res, ErrIngredientNotFound); err != nil {
+		return err
+	}
+	if err := touchRecipe(tx, i.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkIngredient enforces the ingredients table constraints.
+func checkIngredient(i *Ingredient) error {
+	if i.Quantity < 0 || i.Item == "" {
+		return ErrCheckViolation
+	}
+	return nil
+}
+
+// findIngredient returns the index of the specified ingredient within its
+// recipe, or -1 if it does not exist (the caller holds the lock).
+func (s *MemoryStore) findIngredient(i *Ingredient) int {
+	if _, ok := s.recipes[i.RecipeID]; !ok {
+		return -1
+	}
+	for n, existing := range s.ingredients[i.RecipeID] {
+		if existing.ID == i.ID {
+			return n
+		}
+	}
+	return -1
+}
+
+// GetIngredients returns the ingredients of a specific recipe.
+func (s *MemoryStore) GetIngredients(recipeID int) ([]Ingredient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	return append([]Ingredient{}, s.ingredients[recipeID]...), nil
+}
+
+// GetIngredient returns a single specified ingredient.
+func (s *MemoryStore) GetIngredient(i *Ingredient) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.findIngredient(i)
+	if n < 0 {
+		return ErrIngredientNotFound
+	}
+	*i = s.ingredients[i.RecipeID][n]
+	return nil
+}
+
+// AddIngredient adds an ingredient to a specific recipe.
+func (s *MemoryStore) AddIngredient(i *Ingredient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[i.RecipeID]; !ok {
+		return ErrRecipeNotFound
+	}
+	if err := checkIngredient(i); err != nil {
+		return err
+	}
+	i.ID = s.nextIngredientID
+	s.nextIngredientID++
+	s.ingredients[i.RecipeID] = append(s.ingredients[i.RecipeID], *i)
+	s.touch(i.RecipeID)
+	return nil
+}
+
+// UpdateIngredient is used to modify a specific ingredient.
+func (s *MemoryStore) UpdateIngredient(i *Ingredient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findIngredient(i)
+	if n < 0 {
+		return ErrIngredientNotFound
+	}
+	if err := checkIngredient(i); err != nil {
+		return err
+	}
+	s.ingredients[i.RecipeID][n] = *i
+	s.touch(i.RecipeID)
+	return nil
+}
+
+// DeleteIngredient is used to remove a specific ingredient.
+func (s *MemoryStore) DeleteIngredient(i *Ingredient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findIngredient(i)
+	if n < 0 {
+		return ErrIngredientNotFound
+	}
+	ingredients := s.ingredients[i.RecipeID]
+	s.ingredients[i.RecipeID] = append(ingredients[:n:n], ingredients[n+1:]...)
+	s.touch(i.RecipeID)
+	return nil
+}
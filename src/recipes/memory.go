@@ -0,0 +1,178 @@
+package recipes
+
+import (
+	"math"
+	"sync"
+)
+
+// MemoryStore is a RecipeStore held entirely in memory. It is safe for
+// concurrent use and enforces the same constraints as the database tables.
+type MemoryStore struct {
+	mu               sync.RWMutex
+	recipes          map[int]Recipe
+	trash            map[int]Recipe         // deleted recipes, keyed by recipe ID
+	ratings          map[int][]RecipeRating // keyed by recipe ID
+	reviews          map[int][]Review       // keyed by recipe ID, oldest first
+	reviewFlags      map[int][]string       // usernames, keyed by review ID
+	ingredients      map[int][]Ingredient   // keyed by recipe ID
+	steps            map[int][]Step         // keyed by recipe ID, in order
+	tags             map[int][]string       // keyed by recipe ID, in name order
+	terms            map[int]Term           // keyed by term ID
+	recipeTerms      map[int][]int          // term IDs, keyed by recipe ID
+	revisions        map[int][]Revision     // keyed by recipe ID, oldest first
+	users            map[string]User        // keyed by username
+	apiKeys          map[int]APIKey         // keyed by key ID
+	nextRecipeID     int
+	nextRatingID     int
+	nextReviewID     int
+	nextIngredientID int
+	nextStepID       int
+	nextTermID       int
+	nextUserID       int
+	nextAPIKeyID     int
+}
+
+// NewMemoryStore returns a MemoryStore which is empty (apart from the DefaultTerms).
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{
+		recipes:          map[int]Recipe{},
+		trash:            map[int]Recipe{},
+		ratings:          map[int][]RecipeRating{},
+		reviews:          map[int][]Review{},
+		reviewFlags:      map[int][]string{},
+		ingredients:      map[int][]Ingredient{},
+		steps:            map[int][]Step{},
+		tags:             map[int][]string{},
+		terms:            map[int]Term{},
+		recipeTerms:      map[int][]int{},
+		revisions:        map[int][]Revision{},
+		users:            map[string]User{},
+		apiKeys:          map[int]APIKey{},
+		nextRecipeID:     1,
+		nextRatingID:     1,
+		nextReviewID:     1,
+		nextIngredientID: 1,
+		nextStepID:       1,
+		nextTermID:       1,
+		nextUserID:       1,
+		nextAPIKeyID:     1,
+	}
+	for _, taxonomy := range Taxonomies {
+		for _, name := range DefaultTerms[taxonomy] {
+			s.addTerm(&Term{Taxonomy: taxonomy, Name: name})
+		}
+	}
+	return s
+}
+
+// checkRecipe enforces the recipes table constraints (the caller holds the lock).
+func (s *MemoryStore) checkRecipe(r *Recipe) error {
+	if r.Difficulty < 1 || r.Difficulty > 3 || r.Servings < 0 {
+		return ErrCheckViolation
+	}
+	for _, recipes := range []map[int]Recipe{s.recipes, s.trash} {
+		for id, existing := range recipes {
+			if id != r.ID && existing.Name == r.Name {
+				return ErrDuplicateRecipe
+			}
+		}
+	}
+	return nil
+}
+
+// avgRating returns the average rating of a recipe (to 4 decimal places, as
+// avgRatingColumn does), or 0 if it is unrated (the caller holds the lock).
+func (s *MemoryStore) avgRating(recipeID int) float64 {
+	ratings := s.ratings[recipeID]
+	if len(ratings) == 0 {
+		return 0
+	}
+	var sum int
+	for _, rr := range ratings {
+		sum += rr.Rating
+	}
+	return math.Round(float64(sum)/float64(len(ratings))*1e4) / 1e4
+}
+
+// touch advances the version of a recipe, when something shown along with
+// it (an ingredient, step, tag or term) changes (the caller holds the lock).
+func (s *MemoryStore) touch(recipeID int) {
+	if r, ok := s.recipes[recipeID]; ok {
+		r.Version++
+		s.recipes[recipeID] = r
+	}
+}
+
+// GetRecipe returns a single specified recipe.
+func (s *MemoryStore) GetRecipe(r *Recipe) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found, ok := s.recipes[r.ID]
+	if !ok {
+		return ErrRecipeNotFound
+	}
+	*r = found
+	return nil
+}
+
+// UpdateRecipe is used to modify a specific recipe, advancing its version
+// and recording a revision. If a version is specified, the recipe is only
+// modified if it is still that version (and ErrVersionConflict is returned if not).
+func (s *MemoryStore) UpdateRecipe(r *Recipe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.recipes[r.ID]
+	if !ok {
+		return ErrRecipeNotFound
+	}
+	if r.Version != 0 && r.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	if err := s.checkRecipe(r); err != nil {
+		return err
+	}
+	r.CreatedAt = existing.CreatedAt
+	r.Owner = existing.Owner
+	r.Version = existing.Version + 1
+	s.recipes[r.ID] = *r
+	s.addRevision(r, diffRecipes(&existing, r))
+	return nil
+}
+
+// DeleteRecipe is used to move a specific recipe to the trash (from which
+// it may be restored until it is purged). If a version is specified, the
+// recipe is only deleted if it is still that version (and
+// ErrVersionConflict is returned if not).
+func (s *MemoryStore) DeleteRecipe(r *Recipe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.recipes[r.ID]
+	if !ok {
+		return ErrRecipeNotFound
+	}
+	if r.Version != 0 && r.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	deletedAt := now()
+	existing.DeletedAt = &deletedAt
+	delete(s.recipes, r.ID)
+	s.trash[r.ID] = existing
+	return nil
+}
+
+// CreateRecipe is used to create a single recipe, recording its first revision.
+func (s *MemoryStore) CreateRecipe(r *Recipe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.ID = 0
+	if err := s.checkRecipe(r); err != nil {
+		return err
+	}
+	r.ID = s.nextRecipeID
+	s.nextRecipeID++
+	r.CreatedAt = now()
+	r.Version = 1
+	s.recipes[r.ID] = *r
+	s.addRevision(r, diffRecipes(nil, r))
+	return nil
+}
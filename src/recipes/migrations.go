@@ -0,0 +1,584 @@
+package recipes
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// A Migration is a versioned change to the database schema, along with
+// the statements needed to apply (Up) and revert (Down) it for each driver.
+type Migration struct {
+	Version      int
+	Description  string
+	PostgresUp   string
+	PostgresDown string
+	SQLiteUp     string
+	SQLiteDown   string
+}
+
+// MigrationStatus reports whether a specific migration has been applied.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator is implemented by the stores that have a database schema.
+type Migrator interface {
+	// MigrateUp applies all outstanding migrations, returning those applied.
+	MigrateUp() ([]Migration, error)
+	// MigrateDown reverts the most recently applied migration, returning it
+	// (or nil if there was nothing to revert).
+	MigrateDown() (*Migration, error)
+	// MigrationStatus reports the status of every known migration.
+	MigrationStatus() ([]MigrationStatus, error)
+}
+
+// Migrations lists every schema change, in version order. New migrations
+// must only ever be appended (never edited once released).
+var Migrations = []Migration{
+	{
+		Version:     1,
+		Description: "create recipes table",
+		// IF NOT EXISTS allows databases which were created by hand to be adopted
+		PostgresUp: `CREATE TABLE IF NOT EXISTS recipes
+(
+	id BIGSERIAL,
+	name TEXT NOT NULL UNIQUE,
+	preptime FLOAT(4) NOT NULL DEFAULT 0.0,
+	difficulty NUMERIC(1) NOT NULL CHECK (difficulty > 0) CHECK (difficulty < 4) DEFAULT 0,
+	vegetarian BOOLEAN NOT NULL DEFAULT false,
+	CONSTRAINT recipes_pkey PRIMARY KEY (id)
+)`,
+		PostgresDown: `DROP TABLE IF EXISTS recipes`,
+		SQLiteUp: `CREATE TABLE IF NOT EXISTS recipes
+(
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE,
+	preptime REAL NOT NULL DEFAULT 0.0,
+	difficulty INTEGER NOT NULL CHECK (difficulty > 0) CHECK (difficulty < 4) DEFAULT 0,
+	vegetarian BOOLEAN NOT NULL DEFAULT false
+)`,
+		SQLiteDown: `DROP TABLE IF EXISTS recipes`,
+	},
+	{
+		Version:     2,
+		Description: "create recipe_ratings table",
+		PostgresUp: `CREATE TABLE IF NOT EXISTS recipe_ratings
+(
+	recipe_id BIGINT REFERENCES recipes(id) ON DELETE CASCADE,
+	rating_id BIGSERIAL,
+	rating SMALLINT NOT NULL CHECK (rating > 0) CHECK (rating < 6) DEFAULT 0,
+	PRIMARY KEY (recipe_id, rating_id)
+)`,
+		PostgresDown: `DROP TABLE IF EXISTS recipe_ratings`,
+		SQLiteUp: `CREATE TABLE IF NOT EXISTS recipe_ratings
+(
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	rating_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	rating INTEGER NOT NULL CHECK (rating > 0) CHECK (rating < 6) DEFAULT 0
+)`,
+		SQLiteDown: `DROP TABLE IF EXISTS recipe_ratings`,
+	},
+	{
+		Version:     3,
+		Description: "create ingredients table",
+		PostgresUp: `CREATE TABLE ingredients
+(
+	ingredient_id BIGSERIAL PRIMARY KEY,
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	quantity DOUBLE PRECISION NOT NULL CHECK (quantity >= 0) DEFAULT 0,
+	unit TEXT NOT NULL DEFAULT '',
+	item TEXT NOT NULL CHECK (item <> ''),
+	note TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX ingredients_recipe_id_idx ON ingredients(recipe_id)`,
+		PostgresDown: `DROP TABLE ingredients`,
+		SQLiteUp: `CREATE TABLE ingredients
+(
+	ingredient_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	quantity REAL NOT NULL CHECK (quantity >= 0) DEFAULT 0,
+	unit TEXT NOT NULL DEFAULT '',
+	item TEXT NOT NULL CHECK (item <> ''),
+	note TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX ingredients_recipe_id_idx ON ingredients(recipe_id)`,
+		SQLiteDown: `DROP TABLE ingredients`,
+	},
+	{
+		Version:     4,
+		Description: "create steps table",
+		PostgresUp: `CREATE TABLE steps
+(
+	step_id BIGSERIAL PRIMARY KEY,
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL CHECK (position > 0),
+	text TEXT NOT NULL CHECK (text <> ''),
+	duration FLOAT(4) NOT NULL CHECK (duration >= 0) DEFAULT 0.0,
+	timer INTEGER NOT NULL CHECK (timer >= 0) DEFAULT 0
+);
+CREATE UNIQUE INDEX steps_recipe_id_idx ON steps(recipe_id, position)`,
+		PostgresDown: `DROP TABLE steps`,
+		SQLiteUp: `CREATE TABLE steps
+(
+	step_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL CHECK (position > 0),
+	text TEXT NOT NULL CHECK (text <> ''),
+	duration REAL NOT NULL CHECK (duration >= 0) DEFAULT 0.0,
+	timer INTEGER NOT NULL CHECK (timer >= 0) DEFAULT 0
+);
+CREATE UNIQUE INDEX steps_recipe_id_idx ON steps(recipe_id, position)`,
+		SQLiteDown: `DROP TABLE steps`,
+	},
+	{
+		Version:     5,
+		Description: "add servings to recipes",
+		// Zero means the number of servings is unknown
+		PostgresUp:   `ALTER TABLE recipes ADD COLUMN servings INTEGER NOT NULL CHECK (servings >= 0) DEFAULT 0`,
+		PostgresDown: `ALTER TABLE recipes DROP COLUMN servings`,
+		SQLiteUp:     `ALTER TABLE recipes ADD COLUMN servings INTEGER NOT NULL CHECK (servings >= 0) DEFAULT 0`,
+		SQLiteDown:   `ALTER TABLE recipes DROP COLUMN servings`,
+	},
+	{
+		Version:     6,
+		Description: "create tags and taxonomy tables",
+		PostgresUp: `CREATE TABLE tags
+(
+	tag_id BIGSERIAL PRIMARY KEY,
+	name TEXT NOT NULL UNIQUE CHECK (name <> '')
+);
+CREATE TABLE recipe_tags
+(
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	tag_id BIGINT NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+	PRIMARY KEY (recipe_id, tag_id)
+);
+CREATE INDEX recipe_tags_tag_id_idx ON recipe_tags(tag_id);
+CREATE TABLE taxonomy_terms
+(
+	term_id BIGSERIAL PRIMARY KEY,
+	taxonomy TEXT NOT NULL CHECK (taxonomy IN ('cuisine', 'course')),
+	name TEXT NOT NULL CHECK (name <> ''),
+	UNIQUE (taxonomy, name)
+);
+CREATE TABLE recipe_terms
+(
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	term_id BIGINT NOT NULL REFERENCES taxonomy_terms(term_id) ON DELETE CASCADE,
+	PRIMARY KEY (recipe_id, term_id)
+);
+CREATE INDEX recipe_terms_term_id_idx ON recipe_terms(term_id);
+` + defaultTermsInsertion,
+		PostgresDown: `DROP TABLE recipe_terms;
+DROP TABLE taxonomy_terms;
+DROP TABLE recipe_tags;
+DROP TABLE tags`,
+		SQLiteUp: `CREATE TABLE tags
+(
+	tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL UNIQUE CHECK (name <> '')
+);
+CREATE TABLE recipe_tags
+(
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	tag_id INTEGER NOT NULL REFERENCES tags(tag_id) ON DELETE CASCADE,
+	PRIMARY KEY (recipe_id, tag_id)
+);
+CREATE INDEX recipe_tags_tag_id_idx ON recipe_tags(tag_id);
+CREATE TABLE taxonomy_terms
+(
+	term_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	taxonomy TEXT NOT NULL CHECK (taxonomy IN ('cuisine', 'course')),
+	name TEXT NOT NULL CHECK (name <> ''),
+	UNIQUE (taxonomy, name)
+);
+CREATE TABLE recipe_terms
+(
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	term_id INTEGER NOT NULL REFERENCES taxonomy_terms(term_id) ON DELETE CASCADE,
+	PRIMARY KEY (recipe_id, term_id)
+);
+CREATE INDEX recipe_terms_term_id_idx ON recipe_terms(term_id);
+` + defaultTermsInsertion,
+		SQLiteDown: `DROP TABLE recipe_terms;
+DROP TABLE taxonomy_terms;
+DROP TABLE recipe_tags;
+DROP TABLE tags`,
+	},
+	{
+		Version:      7,
+		Description:  "add created_at to recipes",
+		PostgresUp:   `ALTER TABLE recipes ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP`,
+		PostgresDown: `ALTER TABLE recipes DROP COLUMN created_at`,
+		// SQLite can only add columns with constant defaults (recipes are created with the time)
+		SQLiteUp: `ALTER TABLE recipes ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE recipes SET created_at = CURRENT_TIMESTAMP`,
+		SQLiteDown: `ALTER TABLE recipes DROP COLUMN created_at`,
+	},
+	{
+		Version:     8,
+		Description: "enable trigram matching of recipe names",
+		// the extension is left in place, as other databases may depend upon it
+		PostgresUp:   `CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		PostgresDown: ``,
+		// SQLite matches names in-process (see Similarity)
+		SQLiteUp:   ``,
+		SQLiteDown: ``,
+	},
+	{
+		Version:      9,
+		Description:  "add version to recipes",
+		PostgresUp:   `ALTER TABLE recipes ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		PostgresDown: `ALTER TABLE recipes DROP COLUMN version`,
+		SQLiteUp:     `ALTER TABLE recipes ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+		SQLiteDown:   `ALTER TABLE recipes DROP COLUMN version`,
+	},
+	{
+		Version:     10,
+		Description: "create recipe_revisions table",
+		// the existing recipes start their history as they are now
+		PostgresUp: `CREATE TABLE recipe_revisions
+(
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL CHECK (revision > 0),
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	preptime FLOAT(4) NOT NULL,
+	difficulty INTEGER NOT NULL,
+	vegetarian BOOLEAN NOT NULL,
+	servings INTEGER NOT NULL,
+	author TEXT NOT NULL DEFAULT '',
+	changes TEXT NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (recipe_id, revision)
+);
+INSERT INTO recipe_revisions(recipe_id, revision, version, name, preptime, difficulty, vegetarian, servings, created_at)
+	SELECT id, 1, version, name, preptime, difficulty, vegetarian, servings, created_at FROM recipes`,
+		PostgresDown: `DROP TABLE recipe_revisions`,
+		SQLiteUp: `CREATE TABLE recipe_revisions
+(
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	revision INTEGER NOT NULL CHECK (revision > 0),
+	version INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	preptime REAL NOT NULL,
+	difficulty INTEGER NOT NULL,
+	vegetarian BOOLEAN NOT NULL,
+	servings INTEGER NOT NULL,
+	author TEXT NOT NULL DEFAULT '',
+	changes TEXT NOT NULL DEFAULT '{}',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (recipe_id, revision)
+);
+INSERT INTO recipe_revisions(recipe_id, revision, version, name, preptime, difficulty, vegetarian, servings, created_at)
+	SELECT id, 1, version, name, preptime, difficulty, vegetarian, servings, created_at FROM recipes`,
+		SQLiteDown: `DROP TABLE recipe_revisions`,
+	},
+	{
+		Version:     11,
+		Description: "add deleted_at to recipes",
+		// recipes in the trash have been deleted (null for every other recipe)
+		PostgresUp: `ALTER TABLE recipes ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+CREATE INDEX recipes_deleted_at_idx ON recipes(deleted_at)`,
+		PostgresDown: `ALTER TABLE recipes DROP COLUMN deleted_at`,
+		SQLiteUp: `ALTER TABLE recipes ADD COLUMN deleted_at TIMESTAMP;
+CREATE INDEX recipes_deleted_at_idx ON recipes(deleted_at)`,
+		SQLiteDown: `DROP INDEX recipes_deleted_at_idx;
+ALTER TABLE recipes DROP COLUMN deleted_at`,
+	},
+	{
+		Version:     12,
+		Description: "create users table",
+		PostgresUp: `CREATE TABLE users
+(
+	user_id SERIAL PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE CHECK (username <> ''),
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+		PostgresDown: `DROP TABLE users`,
+		SQLiteUp: `CREATE TABLE users
+(
+	user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE CHECK (username <> ''),
+	password_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`,
+		SQLiteDown: `DROP TABLE users`,
+	},
+	{
+		Version:     13,
+		Description: "add role to users and owner to recipes",
+		// the existing recipes have no owner (so may only be modified by editors)
+		PostgresUp: `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'contributor'
+	CHECK (role IN ('viewer', 'contributor', 'editor', 'admin'));
+ALTER TABLE recipes ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+		PostgresDown: `ALTER TABLE recipes DROP COLUMN owner;
+ALTER TABLE users DROP COLUMN role`,
+		SQLiteUp: `ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'contributor'
+	CHECK (role IN ('viewer', 'contributor', 'editor', 'admin'));
+ALTER TABLE recipes ADD COLUMN owner TEXT NOT NULL DEFAULT ''`,
+		SQLiteDown: `ALTER TABLE recipes DROP COLUMN owner;
+ALTER TABLE users DROP COLUMN role`,
+	},
+	{
+		Version:     14,
+		Description: "create api_keys table",
+		PostgresUp: `CREATE TABLE api_keys
+(
+	key_id SERIAL PRIMARY KEY,
+	name TEXT NOT NULL CHECK (name <> ''),
+	username TEXT NOT NULL,
+	scope TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP WITH TIME ZONE,
+	last_used_at TIMESTAMP WITH TIME ZONE
+)`,
+		PostgresDown: `DROP TABLE api_keys`,
+		SQLiteUp: `CREATE TABLE api_keys
+(
+	key_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT NOT NULL CHECK (name <> ''),
+	username TEXT NOT NULL,
+	scope TEXT NOT NULL CHECK (scope IN ('read', 'write')),
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP,
+	last_used_at TIMESTAMP
+)`,
+		SQLiteDown: `DROP TABLE api_keys`,
+	},
+	{
+		Version:     15,
+		Description: "add rater and timestamps to recipe_ratings",
+		// each rater (a user, or an anonymous client) has one rating of each
+		// recipe; the existing ratings have no rater (so are never replaced)
+		PostgresUp: `ALTER TABLE recipe_ratings ADD COLUMN rater TEXT;
+ALTER TABLE recipe_ratings ADD COLUMN username TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipe_ratings ADD COLUMN created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE recipe_ratings ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE UNIQUE INDEX recipe_ratings_rater_idx ON recipe_ratings(recipe_id, rater)`,
+		PostgresDown: `DROP INDEX recipe_ratings_rater_idx;
+ALTER TABLE recipe_ratings DROP COLUMN updated_at;
+ALTER TABLE recipe_ratings DROP COLUMN created_at;
+ALTER TABLE recipe_ratings DROP COLUMN username;
+ALTER TABLE recipe_ratings DROP COLUMN rater`,
+		// SQLite only adds columns with constant defaults
+		SQLiteUp: `ALTER TABLE recipe_ratings ADD COLUMN rater TEXT;
+ALTER TABLE recipe_ratings ADD COLUMN username TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipe_ratings ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+ALTER TABLE recipe_ratings ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT '1970-01-01 00:00:00';
+UPDATE recipe_ratings SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP;
+CREATE UNIQUE INDEX recipe_ratings_rater_idx ON recipe_ratings(recipe_id, rater)`,
+		SQLiteDown: `DROP INDEX recipe_ratings_rater_idx;
+ALTER TABLE recipe_ratings DROP COLUMN updated_at;
+ALTER TABLE recipe_ratings DROP COLUMN created_at;
+ALTER TABLE recipe_ratings DROP COLUMN username;
+ALTER TABLE recipe_ratings DROP COLUMN rater`,
+	},
+	{
+		Version:     16,
+		Description: "create reviews and review_flags tables",
+		PostgresUp: `CREATE TABLE reviews
+(
+	review_id SERIAL PRIMARY KEY,
+	recipe_id BIGINT NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	author TEXT NOT NULL,
+	rating SMALLINT NOT NULL CHECK (rating > 0) CHECK (rating < 6),
+	text TEXT NOT NULL CHECK (text <> ''),
+	hidden BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX reviews_recipe_id_idx ON reviews(recipe_id);
+CREATE TABLE review_flags
+(
+	review_id INTEGER NOT NULL REFERENCES reviews(review_id) ON DELETE CASCADE,
+	username TEXT NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (review_id, username)
+)`,
+		PostgresDown: `DROP TABLE review_flags;
+DROP TABLE reviews`,
+		SQLiteUp: `CREATE TABLE reviews
+(
+	review_id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipe_id INTEGER NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+	author TEXT NOT NULL,
+	rating INTEGER NOT NULL CHECK (rating > 0) CHECK (rating < 6),
+	text TEXT NOT NULL CHECK (text <> ''),
+	hidden BOOLEAN NOT NULL DEFAULT false,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX reviews_recipe_id_idx ON reviews(recipe_id);
+CREATE TABLE review_flags
+(
+	review_id INTEGER NOT NULL REFERENCES reviews(review_id) ON DELETE CASCADE,
+	username TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (review_id, username)
+)`,
+		SQLiteDown: `DROP TABLE review_flags;
+DROP TABLE reviews`,
+	},
+}
+
+// defaultTermsInsertion seeds the taxonomies with DefaultTerms. Like the
+// migrations themselves, it must not be edited once released.
+const defaultTermsInsertion = `INSERT INTO taxonomy_terms(taxonomy, name) VALUES
+	('cuisine', 'american'), ('cuisine', 'british'), ('cuisine', 'chinese'), ('cuisine', 'french'),
+	('cuisine', 'greek'), ('cuisine', 'indian'), ('cuisine', 'italian'), ('cuisine', 'japanese'),
+	('cuisine', 'mexican'), ('cuisine', 'middle eastern'), ('cuisine', 'spanish'), ('cuisine', 'thai'),
+	('course', 'breakfast'), ('course', 'starter'), ('course', 'main'), ('course', 'side'),
+	('course', 'dessert'), ('course', 'snack'), ('course', 'drink')`
+
+const schemaVersionTableCreationQuery = `CREATE TABLE IF NOT EXISTS schema_version
+(
+	version INTEGER PRIMARY KEY,
+	description TEXT NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// up returns the statements which apply the migration for the specified driver.
+func (m *Migration) up(driver string) string {
+	if driver == SQLite {
+		return m.SQLiteUp
+	}
+	return m.PostgresUp
+}
+
+// down returns the statements which revert the migration for the specified driver.
+func (m *Migration) down(driver string) string {
+	if driver == SQLite {
+		return m.SQLiteDown
+	}
+	return m.PostgresDown
+}
+
+// beginMigration starts a transaction, serializing concurrent migrators
+// (for instance several instances starting up at the same time).
+func (s *SQLStore) beginMigration() (*sql.Tx, error) {
+	if _, err := s.DB.Exec(schemaVersionTableCreationQuery); err != nil {
+		return nil, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if s.Driver == Postgres {
+		// SQLite only ever allows a single writer, so no lock is needed
+		if _, err := tx.Exec("LOCK TABLE schema_version IN EXCLUSIVE MODE"); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	return tx, nil
+}
+
+// currentVersion returns the most recently applied migration version (or 0).
+func currentVersion(tx *sql.Tx) (int, error) {
+	var version int
+	err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version)
+	return version, err
+}
+
+// MigrateUp applies all outstanding migrations, each in its own transaction.
+func (s *SQLStore) MigrateUp() ([]Migration, error) {
+	applied := []Migration{}
+	for _, m := range Migrations {
+		tx, err := s.beginMigration()
+		if err != nil {
+			return applied, err
+		}
+		version, err := currentVersion(tx)
+		if err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+		if m.Version <= version {
+			tx.Rollback()
+			continue
+		}
+		if _, err := tx.Exec(m.up(s.Driver)); err != nil {
+			tx.Rollback()
+			return applied, fmt.Errorf("migration %d (%s): %v", m.Version, m.Description, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version(version, description) VALUES($1, $2)",
+			m.Version, m.Description); err != nil {
+			tx.Rollback()
+			return applied, err
+		}
+		if err := tx.Commit(); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// MigrateDown reverts the most recently applied migration.
+func (s *SQLStore) MigrateDown() (*Migration, error) {
+	tx, err := s.beginMigration()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	version, err := currentVersion(tx)
+	if err != nil || version == 0 {
+		return nil, err
+	}
+	for i := range Migrations {
+		m := Migrations[i]
+		if m.Version != version {
+			continue
+		}
+		if _, err := tx.Exec(m.down(s.Driver)); err != nil {
+			return nil, fmt.Errorf("migration %d (%s): %v", m.Version, m.Description, err)
+		}
+		if _, err := tx.Exec("DELETE FROM schema_version WHERE version=$1", m.Version); err != nil {
+			return nil, err
+		}
+		return &m, tx.Commit()
+	}
+	return nil, fmt.Errorf("schema version %d is not a known migration", version)
+}
+
+// MigrationStatus reports the status of every known migration.
+func (s *SQLStore) MigrationStatus() ([]MigrationStatus, error) {
+	if _, err := s.DB.Exec(schemaVersionTableCreationQuery); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appliedAt := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	status := []MigrationStatus{}
+	for _, m := range Migrations {
+		ms := MigrationStatus{Migration: m}
+		if at, ok := appliedAt[m.Version]; ok {
+			ms.AppliedAt = &at
+		}
+		status = append(status, ms)
+	}
+	return status, nil
+}
@@ -1,118 +1,191 @@
 package recipes
 
-import (
-	"database/sql"
-	// GitHub packages
-	"github.com/jmoiron/sqlx"
-)
-
-// The Recipe entity is used to marshall/unmarshall JSON.
+import "time"
+
+// The Recipe entity is used to marshall/unmarshall JSON. CreatedAt and
+// Version are set by the store (Version advancing whenever the recipe, or
+// anything shown along with it, is modified), as is DeletedAt for recipes
+// in the trash. Owner is the user who created the recipe (and is set by the
+// store). ModifiedBy names the user creating or modifying the recipe, who is
+// recorded in the revision made. The validate tags are the rules
+// that a recipe in a request payload must follow.
 type Recipe struct {
-	ID         int     `json:"id"`
-	Name       string  `json:"name"`
-	PrepTime   float32 `json:"preptime"`
-	Difficulty int     `json:"difficulty"`
-	Vegetarian bool    `json:"vegetarian"`
+	ID         int        `json:"id"`
+	Name       string     `json:"name" validate:"required,max=200"`
+	PrepTime   float32    `json:"preptime" validate:"min=0"`
+	Difficulty int        `json:"difficulty" validate:"min=1,max=3"`
+	Vegetarian bool       `json:"vegetarian"`
+	Servings   int        `json:"servings" validate:"min=0"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Version    int        `json:"version"`
+	Owner      string     `json:"owner"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+	ModifiedBy string     `json:"-"`
+}
+
+// The RecipeDetail entity is used to marshall a recipe along with its
+// ingredients, steps, tags and classification.
+type RecipeDetail struct {
+	Recipe
+	Ingredients []Ingredient `json:"ingredients"`
+	Steps       []Step       `json:"steps"`
+	Tags        []string     `json:"tags"`
+	Cuisines    []string     `json:"cuisines"`
+	Courses     []string     `json:"courses"`
+}
+
+// The Ingredient entity is used to marshall/unmarshall JSON (validated as a
+// recipe is).
+type Ingredient struct {
+	ID       int     `json:"ingredient_id"`
+	RecipeID int     `json:"recipe_id"`
+	Quantity float64 `json:"quantity" validate:"min=0"`
+	Unit     string  `json:"unit"`
+	Item     string  `json:"item" validate:"required"`
+	Note     string  `json:"note,omitempty"`
+}
+
+// The Step entity is used to marshall/unmarshall JSON. Duration (in minutes)
+// and Timer (a countdown, in seconds) are optional, but never negative.
+type Step struct {
+	ID       int     `json:"step_id"`
+	RecipeID int     `json:"recipe_id"`
+	Position int     `json:"position"`
+	Text     string  `json:"text" validate:"required"`
+	Duration float32 `json:"duration,omitempty" validate:"min=0"`
+	Timer    int     `json:"timer,omitempty" validate:"min=0"`
+}
+
+// The Tag entity is used to marshall a free-form tag along with the number
+// of recipes carrying it.
+type Tag struct {
+	Name    string `json:"name"`
+	Recipes int    `json:"recipes"`
+}
+
+// The Term entity is used to marshall/unmarshall JSON. Terms make up the
+// curated taxonomies (Cuisine and Course).
+type Term struct {
+	ID       int    `json:"term_id"`
+	Taxonomy string `json:"taxonomy"`
+	Name     string `json:"name" validate:"required,max=50"`
 }
 
-// The RecipeRated entity is used to marshall/unmarshall JSON.
+// The RecipeRated entity is used to marshall/unmarshall JSON. AvgRating is
+// rounded to 4 decimal places. Relevance is only set when searching for text.
 type RecipeRated struct {
-	ID         int     `json:"id"`
-	Name       string  `json:"name"`
-	PrepTime   float32 `json:"preptime"`
-	Difficulty int     `json:"difficulty"`
-	Vegetarian bool    `json:"vegetarian"`
-	AvgRating  float32 `json:"avg_rating"`
+	Recipe
+	AvgRating   float64 `json:"avg_rating"`
+	RatingCount int     `json:"rating_count"`
+	Relevance   float64 `json:"relevance,omitempty"`
 }
 
-// The RecipeRating entity is used to marshall/unmarshall JSON.
-type RecipeRating struct {
-	ID       int `json:"rating_id"`
-	RecipeID int `json:"recipe_id"`
-	Rating   int `json:"rating"`
+// The Suggestion entity is used to marshall/unmarshall JSON. Score is how
+// closely the recipe name matches the prefix suggested for (1 if it starts with it).
+type Suggestion struct {
+	ID    int     `json:"id"`
+	Name  string  `json:"name"`
+	Score float64 `json:"score"`
 }
 
-// GetRecipe returns a single specified recipe.
-func (r *Recipe) GetRecipe(db *sqlx.DB) error {
-	return db.QueryRow("SELECT name, preptime, difficulty, vegetarian FROM recipes WHERE id=$1",
-		r.ID).Scan(&r.Name, &r.PrepTime, &r.Difficulty, &r.Vegetarian)
+// The Revision entity is used to marshall a recorded change to a recipe:
+// the recipe as the change left it, who made it and when, and the fields
+// it changed. Revisions are numbered from 1 for each recipe.
+type Revision struct {
+	RecipeID  int               `json:"recipe_id"`
+	Revision  int               `json:"revision"`
+	Recipe    Recipe            `json:"recipe"`
+	Author    string            `json:"author"`
+	Changes   map[string]Change `json:"changes"`
+	CreatedAt time.Time         `json:"created_at"`
 }
 
-// UpdateRecipe is used to modify a specific recipe.
-func (r *Recipe) UpdateRecipe(db *sqlx.DB) (res sql.Result, err error) {
-	res, err = db.Exec("UPDATE recipes SET name=$1, preptime=$2, difficulty=$3, vegetarian=$4 WHERE id=$5",
-		r.Name, r.PrepTime, r.Difficulty, r.Vegetarian, r.ID)
-	return res, err
+// The Change entity is used to marshall the previous and new values of a
+// field of a recipe (From being null when the recipe was created).
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
 }
 
-// DeleteRecipe is used to delete a specific recipe.
-func (r *Recipe) DeleteRecipe(db *sqlx.DB) (res sql.Result, err error) {
-	res, err = db.Exec("DELETE FROM recipes WHERE id=$1", r.ID)
-	return res, err
+// The roles of users, each of which may do anything that those before it may:
+// viewers may only read, contributors may also create recipes (and modify
+// their own), editors may modify any recipe and the taxonomies, and admins
+// may also purge recipes and manage users.
+const (
+	Viewer      = "viewer"
+	Contributor = "contributor"
+	Editor      = "editor"
+	Admin       = "admin"
+)
+
+// Roles lists every role, from the least to the most privileged.
+var Roles = []string{Viewer, Contributor, Editor, Admin}
+
+// The User entity is used to marshall a user account. The password itself
+// is never stored, only its (bcrypt) hash, which is never marshalled.
+type User struct {
+	ID           int       `json:"user_id"`
+	Username     string    `json:"username"`
+	Role         string    `json:"role"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
 }
 
-// CreateRecipe is used to create a single recipe.
-func (r *Recipe) CreateRecipe(db *sqlx.DB) error {
-	err := db.QueryRow(
-		"INSERT INTO recipes(name, preptime, difficulty, vegetarian) VALUES($1, $2, $3, $4) RETURNING id",
-		r.Name, r.PrepTime, r.Difficulty, r.Vegetarian).Scan(&r.ID)
-	return err
+// The scopes of API keys: read keys may only read, while write keys may do
+// whatever the user they were issued for may.
+const (
+	ReadScope  = "read"
+	WriteScope = "write"
+)
+
+// The APIKey entity is used to marshall/unmarshall an API key, which is
+// issued for a (machine) client to act as a user. Only a hash of the key is
+// stored, so the Key itself is only ever marshalled when it is created.
+type APIKey struct {
+	ID         int        `json:"key_id"`
+	Name       string     `json:"name"`
+	Username   string     `json:"username"`
+	Scope      string     `json:"scope"`
+	Key        string     `json:"key,omitempty"`
+	Prefix     string     `json:"prefix"`
+	KeyHash    string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
 }
 
-// GetRecipes returns a collection of known recipes.
-func GetRecipes(db *sqlx.DB, start int, count int) ([]Recipe, error) {
-	rows, err := db.Query(
-		"SELECT id, name, preptime, difficulty, vegetarian FROM recipes ORDER BY name LIMIT $1 OFFSET $2",
-		count, start)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-	recipes := []Recipe{}
-	for rows.Next() {
-		var r Recipe
-		if err := rows.Scan(&r.ID, &r.Name, &r.PrepTime, &r.Difficulty, &r.Vegetarian); err != nil {
-			return nil, err
-		}
-		recipes = append(recipes, r)
-	}
-
-	return recipes, nil
+// The RecipeRating entity is used to marshall/unmarshall JSON. Rater
+// identifies who gave the rating (a user, or an anonymous client), each of
+// whom has at most one rating of a recipe. Username is "" for anonymous
+// clients.
+type RecipeRating struct {
+	ID        int       `json:"rating_id"`
+	RecipeID  int       `json:"recipe_id"`
+	Rating    int       `json:"rating" validate:"min=1,max=5"`
+	Rater     string    `json:"-"`
+	Username  string    `json:"username,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// GetRecipesRated returns a collection of rated recipes.
-func GetRecipesRated(db *sqlx.DB, start int, count int, preptime float32) ([]RecipeRated, error) {
-	rows, err := db.Query(
-		"SELECT id, name, preptime, difficulty, vegetarian, "+
-			"(SELECT COALESCE(AVG(rating),0) AS avg_rating FROM recipe_ratings WHERE recipe_id = id)"+
-			" FROM recipes WHERE preptime < $1 ORDER BY name LIMIT $2 OFFSET $3",
-		preptime, count, start)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-	recipesRated := []RecipeRated{}
-	for rows.Next() {
-		var rr RecipeRated
-		if err := rows.Scan(&rr.ID, &rr.Name, &rr.PrepTime, &rr.Difficulty, &rr.Vegetarian, &rr.AvgRating); err != nil {
-			return nil, err
-		}
-		recipesRated = append(recipesRated, rr)
-	}
-
-	return recipesRated, nil
+// The Review entity is used to marshall/unmarshall JSON. FlagCount is how
+// many users have flagged the review for moderation, and a Hidden review
+// (hidden by an admin) is only shown to editors and admins.
+type Review struct {
+	ID        int       `json:"review_id"`
+	RecipeID  int       `json:"recipe_id"`
+	Author    string    `json:"author"`
+	Rating    int       `json:"rating"`
+	Text      string    `json:"text"`
+	FlagCount int       `json:"flag_count"`
+	Hidden    bool      `json:"hidden"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
-// AddRecipeRating adds a rating for a specific recipe.
-// There can be many ratings for any specific recipe
-// and the ratings are never overwritten.
-func (rr *RecipeRating) AddRecipeRating(db *sqlx.DB) error {
-	err := db.QueryRow(
-		"INSERT INTO recipe_ratings(recipe_id, rating) VALUES($1, $2) RETURNING rating_id",
-		rr.RecipeID, rr.Rating).Scan(&rr.ID)
-	return err
+// ReviewPage is a page of the reviews of a recipe, along with the total
+// number of them.
+type ReviewPage struct {
+	Reviews []Review
+	Total   int
 }
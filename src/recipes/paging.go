@@ -0,0 +1,215 @@
+package recipes
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Page requests a page of (at most) Count recipes: those following the
+// Cursor, or preceding it if the cursor is Before, or otherwise those
+// following the first Start recipes (the original offset paging).
+type Page struct {
+	Start  int
+	Count  int
+	Cursor *Cursor
+}
+
+// RecipePage is a page of recipes, along with the total number of recipes
+// which match the filter and the cursors of the neighbouring pages (which
+// are nil where there is no such page).
+type RecipePage struct {
+	Recipes []RecipeRated
+	Total   int
+	Next    *Cursor
+	Prev    *Cursor
+}
+
+// A Cursor marks a position in the sorted recipes, immediately after (or,
+// if Before, immediately before) the recipe with the sort key Value and ID.
+// Keyset paging with cursors is stable as recipes are added and removed,
+// unlike offset paging.
+type Cursor struct {
+	Sort   RecipeSort
+	Value  interface{} // as returned by RecipeSort.key
+	ID     int
+	Before bool
+}
+
+// cursorToken is the JSON encoding of a cursor (within its opaque token).
+type cursorToken struct {
+	Sort   string      `json:"s"`
+	Value  interface{} `json:"v"`
+	ID     int         `json:"id"`
+	Before bool        `json:"b,omitempty"`
+}
+
+// Token returns the cursor as an opaque (URL safe) token.
+func (c *Cursor) Token() string {
+	b, _ := json.Marshal(cursorToken{c.Sort.String(), c.Value, c.ID, c.Before})
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseCursor parses a token returned by Cursor.Token, returning
+// ErrInvalidCursor if it is malformed.
+func ParseCursor(token string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var t cursorToken
+	if err := json.Unmarshal(b, &t); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	o, err := ParseSort(t.Sort)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	c := &Cursor{Sort: o, ID: t.ID, Before: t.Before}
+	// JSON decodes the value as a string or a float64, according to the field
+	var ok bool
+	switch o.Field {
+	case "name":
+		c.Value, ok = t.Value.(string)
+	case "created_at":
+		var value string
+		if value, ok = t.Value.(string); ok {
+			c.Value, err = time.Parse(time.RFC3339Nano, value)
+			ok = err == nil
+		}
+	default:
+		c.Value, ok = t.Value.(float64)
+	}
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// condition returns the SQL condition selecting the recipes beyond the
+// cursor (in the direction of paging). Recipes are ordered by the sort field
+// and then by ID in the same direction, so comparing the pair selects them.
+func (c *Cursor) condition(q *recipeQuery) string {
+	o := c.Sort.resolve(RecipeFilter{})
+	op := " > "
+	if o.Descending != c.Before {
+		op = " < "
+	}
+	return "(" + o.Field + ", id)" + op + "(" + q.bind(c.Value) + ", " + q.bind(c.ID) + ")"
+}
+
+// setCursors sets the cursors of the pages either side of the recipes (where
+// there are such pages).
+func (p *RecipePage) setCursors(o RecipeSort, hasPrev, hasNext bool) {
+	if len(p.Recipes) == 0 {
+		return
+	}
+	if first := &p.Recipes[0]; hasPrev {
+		p.Prev = &Cursor{Sort: o, Value: o.key(first), ID: first.ID, Before: true}
+	}
+	if last := &p.Recipes[len(p.Recipes)-1]; hasNext {
+		p.Next = &Cursor{Sort: o, Value: o.key(last), ID: last.ID}
+	}
+}
+
+// GetRecipesPage returns a page of the recipes which match the filter, in order.
+func (s *SQLStore) GetRecipesPage(f RecipeFilter, o RecipeSort, p Page) (RecipePage, error) {
+	page := RecipePage{Recipes: []RecipeRated{}}
+	o = o.resolve(f)
+	if p.Cursor != nil {
+		o = p.Cursor.Sort.resolve(f)
+	}
+	q := &recipeQuery{}
+	if err := s.DB.QueryRow("SELECT COUNT(*)"+s.matching(f, q, nil), q.args...).Scan(&page.Total); err != nil {
+		return page, err
+	}
+
+	// a page preceding the cursor is read backwards, from the cursor
+	order := o
+	if p.Cursor != nil && p.Cursor.Before {
+		order.Descending = !order.Descending
+	}
+	// one more recipe than the page holds is read, to tell if there are more
+	q = &recipeQuery{}
+	query := "SELECT id, name, preptime, difficulty, vegetarian, servings, created_at, version, owner, avg_rating, rating_count, relevance" +
+		s.matching(f, q, p.Cursor) + order.orderBy(f) + " LIMIT " + q.bind(p.Count+1)
+	if p.Cursor == nil {
+		query += " OFFSET " + q.bind(p.Start)
+	}
+	rows, err := s.DB.Query(query, q.args...)
+	if err != nil {
+		return page, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var rr RecipeRated
+		if err := rows.Scan(&rr.ID, &rr.Name, &rr.PrepTime, &rr.Difficulty, &rr.Vegetarian, &rr.Servings, &rr.CreatedAt,
+			&rr.Version, &rr.Owner, &rr.AvgRating, &rr.RatingCount, &rr.Relevance); err != nil {
+			return page, err
+		}
+		page.Recipes = append(page.Recipes, rr)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	more := len(page.Recipes) > p.Count
+	if more {
+		page.Recipes = page.Recipes[:p.Count]
+	}
+	// there is taken to be a page on the far side of a cursor (where it came from)
+	switch {
+	case p.Cursor == nil:
+		page.setCursors(o, p.Start > 0, more)
+	case p.Cursor.Before:
+		for i, j := 0, len(page.Recipes)-1; i < j; i, j = i+1, j-1 {
+			page.Recipes[i], page.Recipes[j] = page.Recipes[j], page.Recipes[i]
+		}
+		page.setCursors(o, more, true)
+	default:
+		page.setCursors(o, true, more)
+	}
+	return page, nil
+}
+
+// GetRecipesPage returns a page of the recipes which match the filter, in order.
+func (s *MemoryStore) GetRecipesPage(f RecipeFilter, o RecipeSort, p Page) (RecipePage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	o = o.resolve(f)
+	if p.Cursor != nil {
+		o = p.Cursor.Sort.resolve(f)
+	}
+	matched := s.query(f, o)
+
+	start, end := p.Start, p.Start+p.Count
+	if c := p.Cursor; c != nil {
+		// the index of the first recipe following the cursor
+		i := sort.Search(len(matched), func(i int) bool {
+			return o.compare(o.key(&matched[i]), matched[i].ID, c.Value, c.ID) > 0
+		})
+		start, end = i, i+p.Count
+		if c.Before {
+			// the index of the first recipe at (or following) the cursor
+			end = sort.Search(len(matched), func(i int) bool {
+				return o.compare(o.key(&matched[i]), matched[i].ID, c.Value, c.ID) >= 0
+			})
+			start = end - p.Count
+		}
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > len(matched) {
+		end = len(matched)
+	}
+	if start > end {
+		start = end
+	}
+
+	page := RecipePage{Recipes: matched[start:end], Total: len(matched)}
+	page.setCursors(o, start > 0, end < len(matched))
+	return page, nil
+}
@@ -0,0 +1,29 @@
+package recipes
+
+import (
+	// GitHub packages
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// NewPostgresStore returns an SQLStore using the supplied PostgreSQL connection.
+func NewPostgresStore(db *sqlx.DB) *SQLStore {
+	return &SQLStore{DB: db, Driver: Postgres}
+}
+
+// mapPostgresError translates Postgres constraint violations into store errors.
+func mapPostgresError(err error) error {
+	pqErr, ok := err.(*pq.Error)
+	if !ok {
+		return err
+	}
+	switch pqErr.Code {
+	case "23505": // unique_violation
+		return ErrUniqueViolation
+	case "23514": // check_violation
+		return ErrCheckViolation
+	case "23503": // foreign_key_violation
+		return ErrReferenceViolation
+	}
+	return err
+}
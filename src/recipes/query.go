@@ -0,0 +1,301 @@
+package recipes
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecipeFilter restricts the recipes listed or searched for. A recipe must
+// satisfy every one of the restrictions which is set: it must match the text,
+// carry every one of the tags, be classified under every one of the cuisines
+// and courses, and have one of the difficulties listed.
+type RecipeFilter struct {
+	Text           string
+	Tags           []string
+	Cuisines       []string
+	Courses        []string
+	MinPrepTime    *float32 // inclusive
+	MaxPrepTime    *float32 // inclusive
+	PrepTimeBelow  *float32 // exclusive (the original search parameter)
+	Difficulties   []int
+	Vegetarian     *bool
+	MinAvgRating   float64
+	MinRatingCount int
+}
+
+// SortFields lists the fields which recipes may be sorted by (relevance is
+// only of use when searching for text). They are also the column names
+// provided by matching.
+var SortFields = []string{"name", "preptime", "difficulty", "avg_rating", "rating_count", "created_at", "relevance"}
+
+// RecipeSort orders the recipes listed or searched for. Recipes which sort
+// equally are ordered by ID (in the same direction), so the order is stable.
+// The zero value sorts by relevance when searching for text, and otherwise by name.
+type RecipeSort struct {
+	Field      string
+	Descending bool
+}
+
+// ParseSort parses a sort order such as "preptime" or "-avg_rating" (where
+// the leading "-" means descending), returning ErrInvalidSort if the field
+// is not one of SortFields.
+func ParseSort(s string) (RecipeSort, error) {
+	o := RecipeSort{Field: strings.TrimPrefix(s, "-"), Descending: strings.HasPrefix(s, "-")}
+	if !isSortField(o.Field) {
+		return RecipeSort{}, ErrInvalidSort
+	}
+	return o, nil
+}
+
+// isSortField reports whether the field is one of SortFields.
+func isSortField(field string) bool {
+	for _, f := range SortFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the sort order to use with the filter.
+func (o RecipeSort) resolve(f RecipeFilter) RecipeSort {
+	switch {
+	case isSortField(o.Field):
+		return o
+	case f.Text != "":
+		return RecipeSort{Field: "relevance", Descending: true}
+	}
+	return RecipeSort{Field: "name"}
+}
+
+// orderBy returns the ORDER BY clause for the sort order. Only SortFields
+// are ever interpolated into the SQL.
+func (o RecipeSort) orderBy(f RecipeFilter) string {
+	o = o.resolve(f)
+	direction := " ASC"
+	if o.Descending {
+		direction = " DESC"
+	}
+	return " ORDER BY " + o.Field + direction + ", id" + direction
+}
+
+// String returns the sort order in the form parsed by ParseSort.
+func (o RecipeSort) String() string {
+	if o.Descending {
+		return "-" + o.Field
+	}
+	return o.Field
+}
+
+// key returns the value of the sort field of a recipe: a string (name), a
+// time.Time (created_at) or a float64 (any other field).
+func (o RecipeSort) key(rr *RecipeRated) interface{} {
+	switch o.Field {
+	case "name":
+		return rr.Name
+	case "created_at":
+		return rr.CreatedAt
+	case "preptime":
+		return float64(rr.PrepTime)
+	case "difficulty":
+		return float64(rr.Difficulty)
+	case "avg_rating":
+		return rr.AvgRating
+	case "rating_count":
+		return float64(rr.RatingCount)
+	}
+	return rr.Relevance
+}
+
+// compare returns a negative number, 0 or a positive number as the first
+// sort key (and ID) sorts before, equally to or after the second, in the
+// sort order (so taking account of its direction).
+func (o RecipeSort) compare(key1 interface{}, id1 int, key2 interface{}, id2 int) int {
+	c := 0
+	switch k1 := key1.(type) {
+	case string:
+		c = strings.Compare(k1, key2.(string))
+	case time.Time:
+		switch k2 := key2.(time.Time); {
+		case k1.Before(k2):
+			c = -1
+		case k1.After(k2):
+			c = 1
+		}
+	case float64:
+		switch k2 := key2.(float64); {
+		case k1 < k2:
+			c = -1
+		case k1 > k2:
+			c = 1
+		}
+	}
+	if c == 0 {
+		c = id1 - id2
+	}
+	if o.Descending {
+		return -c
+	}
+	return c
+}
+
+// sortRecipes sorts the recipes into the (resolved) sort order.
+func (o RecipeSort) sortRecipes(recipes []RecipeRated) {
+	sort.Slice(recipes, func(i, j int) bool {
+		return o.compare(o.key(&recipes[i]), recipes[i].ID, o.key(&recipes[j]), recipes[j].ID) < 0
+	})
+}
+
+// The SQL expressions for the ratings of each recipe. The average is rounded
+// so that it is represented exactly when it is bound into a page cursor.
+const (
+	avgRatingColumn   = "(SELECT ROUND(COALESCE(AVG(rating), 0), 4) FROM recipe_ratings WHERE recipe_ratings.recipe_id = recipes.id)"
+	ratingCountColumn = "(SELECT COUNT(*) FROM recipe_ratings WHERE recipe_ratings.recipe_id = recipes.id)"
+)
+
+// recipeQuery accumulates the arguments of a query as it is built. The
+// placeholders must appear in the query in the order that they are bound
+// (SQLite numbers "$N" parameters by their first appearance).
+type recipeQuery struct {
+	args []interface{}
+}
+
+// bind appends a query argument, returning its placeholder.
+func (q *recipeQuery) bind(v interface{}) string {
+	q.args = append(q.args, v)
+	return "$" + strconv.Itoa(len(q.args))
+}
+
+// whereClause joins the conditions into a WHERE clause (if there are any).
+func whereClause(conds []string) string {
+	if len(conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(conds, " AND ")
+}
+
+// matching returns the FROM clause of a query selecting the recipes which
+// match the filter (and lie beyond the cursor, if there is one). As well as
+// the columns of the recipes table, it provides avg_rating, rating_count and
+// relevance (which is 0 unless searching for text).
+func (s *SQLStore) matching(f RecipeFilter, q *recipeQuery, c *Cursor) string {
+	relevance := "0"
+	conds := []string{"deleted_at IS NULL"}
+	if f.Text != "" {
+		relevance = s.relevance(q.bind(f.Text))
+		if s.Driver == SQLite {
+			conds = append(conds, s.relevance(q.bind(f.Text))+" > 0")
+		} else {
+			conds = append(conds, postgresDocument+" @@ plainto_tsquery('english', "+q.bind(f.Text)+")")
+		}
+	}
+	for _, tag := range f.Tags {
+		conds = append(conds, "EXISTS (SELECT 1 FROM recipe_tags rt JOIN tags t ON t.tag_id = rt.tag_id "+
+			"WHERE rt.recipe_id = recipes.id AND t.name = "+q.bind(normalizeName(tag))+")")
+	}
+	terms := map[string][]string{Cuisine: f.Cuisines, Course: f.Courses}
+	for _, taxonomy := range Taxonomies {
+		for _, term := range terms[taxonomy] {
+			conds = append(conds, "EXISTS (SELECT 1 FROM recipe_terms rt JOIN taxonomy_terms tt ON tt.term_id = rt.term_id "+
+				"WHERE rt.recipe_id = recipes.id AND tt.taxonomy = "+q.bind(taxonomy)+
+				" AND tt.name = "+q.bind(normalizeName(term))+")")
+		}
+	}
+	if f.MinPrepTime != nil {
+		conds = append(conds, "preptime >= "+q.bind(*f.MinPrepTime))
+	}
+	if f.MaxPrepTime != nil {
+		conds = append(conds, "preptime <= "+q.bind(*f.MaxPrepTime))
+	}
+	if f.PrepTimeBelow != nil {
+		conds = append(conds, "preptime < "+q.bind(*f.PrepTimeBelow))
+	}
+	if len(f.Difficulties) > 0 {
+		placeholders := []string{}
+		for _, d := range f.Difficulties {
+			placeholders = append(placeholders, q.bind(d))
+		}
+		conds = append(conds, "difficulty IN ("+strings.Join(placeholders, ", ")+")")
+	}
+	if f.Vegetarian != nil {
+		conds = append(conds, "vegetarian = "+q.bind(*f.Vegetarian))
+	}
+	inner := "SELECT id, name, preptime, difficulty, vegetarian, servings, created_at, version, owner, " +
+		avgRatingColumn + " AS avg_rating, " + ratingCountColumn + " AS rating_count, " +
+		relevance + " AS relevance FROM recipes" + whereClause(conds)
+
+	// the ratings are filtered once they have been calculated
+	conds = []string{}
+	if f.MinAvgRating > 0 {
+		conds = append(conds, "avg_rating >= "+q.bind(f.MinAvgRating))
+	}
+	if f.MinRatingCount > 0 {
+		conds = append(conds, "rating_count >= "+q.bind(f.MinRatingCount))
+	}
+	if c != nil {
+		conds = append(conds, c.condition(q))
+	}
+	return " FROM (" + inner + ") AS matched" + whereClause(conds)
+}
+
+// query returns the recipes which match the filter, along with their ratings
+// and relevance, in order (the caller holds the lock).
+func (s *MemoryStore) query(f RecipeFilter, o RecipeSort) []RecipeRated {
+	matched := []RecipeRated{}
+	for id, r := range s.recipes {
+		if !s.matches(id, f) {
+			continue
+		}
+		rr := RecipeRated{Recipe: r, AvgRating: s.avgRating(id), RatingCount: len(s.ratings[id])}
+		if f.Text != "" {
+			rr.Relevance = s.relevance(id, f.Text)
+		}
+		matched = append(matched, rr)
+	}
+	o.resolve(f).sortRecipes(matched)
+	return matched
+}
+
+// matches reports whether a recipe satisfies the filter (the caller holds the lock).
+func (s *MemoryStore) matches(recipeID int, f RecipeFilter) bool {
+	r := s.recipes[recipeID]
+	switch {
+	case f.MinPrepTime != nil && r.PrepTime < *f.MinPrepTime,
+		f.MaxPrepTime != nil && r.PrepTime > *f.MaxPrepTime,
+		f.PrepTimeBelow != nil && r.PrepTime >= *f.PrepTimeBelow,
+		f.Vegetarian != nil && r.Vegetarian != *f.Vegetarian,
+		f.MinAvgRating > 0 && s.avgRating(recipeID) < f.MinAvgRating,
+		f.MinRatingCount > 0 && len(s.ratings[recipeID]) < f.MinRatingCount:
+		return false
+	}
+	if len(f.Difficulties) > 0 {
+		found := false
+		for _, d := range f.Difficulties {
+			found = found || d == r.Difficulty
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Text != "" && s.relevance(recipeID, f.Text) == 0 {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !s.hasTag(recipeID, normalizeName(tag)) {
+			return false
+		}
+	}
+	for _, term := range f.Cuisines {
+		if !s.hasTerm(recipeID, Cuisine, normalizeName(term)) {
+			return false
+		}
+	}
+	for _, term := range f.Courses {
+		if !s.hasTerm(recipeID, Course, normalizeName(term)) {
+			return false
+		}
+	}
+	return true
+}
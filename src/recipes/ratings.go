@@ -0,0 +1,164 @@
+package recipes
+
+import (
+	"database/sql"
+)
+
+// ratingColumns are the columns selected for each rating, in the order
+// scanned by scanRating.
+const ratingColumns = "rating_id, recipe_id, rating, COALESCE(rater, ''), username, created_at, updated_at"
+
+// scanRating scans the ratingColumns of a row into a rating.
+func scanRating(scan func(dest ...interface{}) error, rr *RecipeRating) error {
+	return scan(&rr.ID, &rr.RecipeID, &rr.Rating, &rr.Rater, &rr.Username, &rr.CreatedAt, &rr.UpdatedAt)
+}
+
+// raterValue is the value stored for a rater, which is null if there is none
+// (so that the rating never takes the place of another).
+func raterValue(rater string) sql.NullString {
+	return sql.NullString{String: rater, Valid: rater != ""}
+}
+
+// GetRecipeRatings returns the ratings of a specific recipe, oldest first.
+func (s *SQLStore) GetRecipeRatings(recipeID int) ([]RecipeRating, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+
+	rows, err := s.DB.Query("SELECT "+ratingColumns+" FROM recipe_ratings WHERE recipe_id=$1 ORDER BY rating_id", recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	ratings := []RecipeRating{}
+	for rows.Next() {
+		var rr RecipeRating
+		if err := scanRating(rows.Scan, &rr); err != nil {
+			return nil, err
+		}
+		ratings = append(ratings, rr)
+	}
+
+	return ratings, rows.Err()
+}
+
+// GetRecipeRating populates the rating of a specific recipe by a specific rater.
+func (s *SQLStore) GetRecipeRating(rr *RecipeRating) error {
+	err := scanRating(s.DB.QueryRow(
+		"SELECT "+ratingColumns+" FROM recipe_ratings WHERE recipe_id=$1 AND rater=$2 "+
+			"AND EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL)",
+		rr.RecipeID, rr.Rater).Scan, rr)
+	if err == sql.ErrNoRows {
+		return ErrRatingNotFound
+	}
+	return err
+}
+
+// SetRecipeRating adds the rating of a specific recipe by a rater, replacing
+// any rating they have already given it (in which case it keeps its ID and
+// CreatedAt). A rating without a rater is always added.
+func (s *SQLStore) SetRecipeRating(rr *RecipeRating) error {
+	at := now()
+	err := s.DB.QueryRow(
+		"INSERT INTO recipe_ratings(recipe_id, rating, rater, username, created_at, updated_at) "+
+			"SELECT $1, $2, $3, $4, $5, $5 WHERE EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL) "+
+			"ON CONFLICT (recipe_id, rater) DO UPDATE SET rating=excluded.rating, username=excluded.username, updated_at=excluded.updated_at "+
+			"RETURNING rating_id, created_at, updated_at",
+		rr.RecipeID, rr.Rating, raterValue(rr.Rater), rr.Username, at).Scan(&rr.ID, &rr.CreatedAt, &rr.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	return s.mapError(err)
+}
+
+// DeleteRecipeRating removes the rating of a specific recipe by a specific rater.
+func (s *SQLStore) DeleteRecipeRating(rr *RecipeRating) error {
+	res, err := s.DB.Exec(
+		"DELETE FROM recipe_ratings WHERE recipe_id=$1 AND rater=$2 "+
+			"AND EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL)",
+		rr.RecipeID, rr.Rater)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res, ErrRatingNotFound)
+}
+
+// findRating returns the index of the rating of a recipe by a rater, or -1
+// (the caller holds the lock).
+func (s *MemoryStore) findRating(recipeID int, rater string) int {
+	if rater == "" {
+		return -1
+	}
+	for n, existing := range s.ratings[recipeID] {
+		if existing.Rater == rater {
+			return n
+		}
+	}
+	return -1
+}
+
+// GetRecipeRatings returns the ratings of a specific recipe, oldest first.
+func (s *MemoryStore) GetRecipeRatings(recipeID int) ([]RecipeRating, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	return append([]RecipeRating{}, s.ratings[recipeID]...), nil
+}
+
+// GetRecipeRating populates the rating of a specific recipe by a specific rater.
+func (s *MemoryStore) GetRecipeRating(rr *RecipeRating) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.findRating(rr.RecipeID, rr.Rater)
+	if _, ok := s.recipes[rr.RecipeID]; !ok || n < 0 {
+		return ErrRatingNotFound
+	}
+	*rr = s.ratings[rr.RecipeID][n]
+	return nil
+}
+
+// SetRecipeRating adds the rating of a specific recipe by a rater, replacing
+// any rating they have already given it (in which case it keeps its ID and
+// CreatedAt). A rating without a rater is always added.
+func (s *MemoryStore) SetRecipeRating(rr *RecipeRating) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[rr.RecipeID]; !ok {
+		return ErrRecipeNotFound
+	}
+	if rr.Rating < 1 || rr.Rating > 5 {
+		return ErrCheckViolation
+	}
+	rr.UpdatedAt = now()
+	if n := s.findRating(rr.RecipeID, rr.Rater); n >= 0 {
+		existing := s.ratings[rr.RecipeID][n]
+		rr.ID, rr.CreatedAt = existing.ID, existing.CreatedAt
+		s.ratings[rr.RecipeID][n] = *rr
+		return nil
+	}
+	rr.ID = s.nextRatingID
+	s.nextRatingID++
+	rr.CreatedAt = rr.UpdatedAt
+	s.ratings[rr.RecipeID] = append(s.ratings[rr.RecipeID], *rr)
+	return nil
+}
+
+// DeleteRecipeRating removes the rating of a specific recipe by a specific rater.
+func (s *MemoryStore) DeleteRecipeRating(rr *RecipeRating) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findRating(rr.RecipeID, rr.Rater)
+	if _, ok := s.recipes[rr.RecipeID]; !ok || n < 0 {
+		return ErrRatingNotFound
+	}
+	ratings := s.ratings[rr.RecipeID]
+	s.ratings[rr.RecipeID] = append(ratings[:n:n], ratings[n+1:]...)
+	return nil
+}
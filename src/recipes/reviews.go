@@ -0,0 +1,290 @@
+package recipes
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// reviewColumns are the columns selected for each review, in the order
+// scanned by scanReview.
+const reviewColumns = "review_id, recipe_id, author, rating, text, " +
+	"(SELECT COUNT(*) FROM review_flags WHERE review_flags.review_id = reviews.review_id), " +
+	"hidden, created_at, updated_at"
+
+// reviewRecipeExists restricts a statement on reviews to those of recipes
+// which are not in the trash.
+const reviewRecipeExists = "recipe_id IN (SELECT id FROM recipes WHERE deleted_at IS NULL)"
+
+// checkReview enforces the reviews table constraints.
+func checkReview(rv *Review) error {
+	if rv.Rating < 1 || rv.Rating > 5 || strings.TrimSpace(rv.Text) == "" {
+		return ErrCheckViolation
+	}
+	return nil
+}
+
+// scanReview scans the reviewColumns of a row into a review.
+func scanReview(scan func(dest ...interface{}) error, rv *Review) error {
+	return scan(&rv.ID, &rv.RecipeID, &rv.Author, &rv.Rating, &rv.Text, &rv.FlagCount, &rv.Hidden, &rv.CreatedAt, &rv.UpdatedAt)
+}
+
+// GetReviews returns a page of (at most count of) the reviews of a specific
+// recipe, newest first, following the first start of them. Hidden reviews
+// are only included if withHidden.
+func (s *SQLStore) GetReviews(recipeID, start, count int, withHidden bool) (ReviewPage, error) {
+	page := ReviewPage{Reviews: []Review{}}
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return page, err
+	}
+	if !exists {
+		return page, ErrRecipeNotFound
+	}
+
+	where := " FROM reviews WHERE recipe_id=$1"
+	if !withHidden {
+		where += " AND NOT hidden"
+	}
+	if err := s.DB.QueryRow("SELECT COUNT(*)"+where, recipeID).Scan(&page.Total); err != nil {
+		return page, err
+	}
+	rows, err := s.DB.Query("SELECT "+reviewColumns+where+" ORDER BY review_id DESC LIMIT $2 OFFSET $3",
+		recipeID, count, start)
+	if err != nil {
+		return page, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var rv Review
+		if err := scanReview(rows.Scan, &rv); err != nil {
+			return page, err
+		}
+		page.Reviews = append(page.Reviews, rv)
+	}
+
+	return page, rows.Err()
+}
+
+// GetReview populates the specified review (by ID and recipe ID).
+func (s *SQLStore) GetReview(rv *Review) error {
+	err := scanReview(s.DB.QueryRow(
+		"SELECT "+reviewColumns+" FROM reviews WHERE review_id=$1 AND recipe_id=$2 AND "+reviewRecipeExists,
+		rv.ID, rv.RecipeID).Scan, rv)
+	if err == sql.ErrNoRows {
+		return ErrReviewNotFound
+	}
+	return err
+}
+
+// AddReview adds a review of a specific recipe.
+func (s *SQLStore) AddReview(rv *Review) error {
+	if err := checkReview(rv); err != nil {
+		return err
+	}
+	rv.CreatedAt = now()
+	rv.UpdatedAt, rv.FlagCount, rv.Hidden = rv.CreatedAt, 0, false
+	err := s.DB.QueryRow(
+		"INSERT INTO reviews(recipe_id, author, rating, text, created_at, updated_at) "+
+			"SELECT $1, $2, $3, $4, $5, $5 WHERE EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL) "+
+			"RETURNING review_id",
+		rv.RecipeID, rv.Author, rv.Rating, rv.Text, rv.CreatedAt).Scan(&rv.ID)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	return s.mapError(err)
+}
+
+// UpdateReview is used to modify the rating and text of a specific review.
+func (s *SQLStore) UpdateReview(rv *Review) error {
+	if err := checkReview(rv); err != nil {
+		return err
+	}
+	res, err := s.DB.Exec(
+		"UPDATE reviews SET rating=$1, text=$2, updated_at=$3 WHERE review_id=$4 AND recipe_id=$5 AND "+reviewRecipeExists,
+		rv.Rating, rv.Text, now(), rv.ID, rv.RecipeID)
+	if err != nil {
+		return s.mapError(err)
+	}
+	if err := checkRowsAffected(res, ErrReviewNotFound); err != nil {
+		return err
+	}
+	return s.GetReview(rv)
+}
+
+// DeleteReview is used to remove a specific review.
+func (s *SQLStore) DeleteReview(rv *Review) error {
+	res, err := s.DB.Exec("DELETE FROM reviews WHERE review_id=$1 AND recipe_id=$2 AND "+reviewRecipeExists,
+		rv.ID, rv.RecipeID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res, ErrReviewNotFound)
+}
+
+// FlagReview records that a user has flagged a specific review (which
+// counts once, however often they do so), populating the review.
+func (s *SQLStore) FlagReview(rv *Review, username string) error {
+	if _, err := s.DB.Exec(
+		"INSERT INTO review_flags(review_id, username, created_at) SELECT $1, $2, $3 "+
+			"WHERE EXISTS(SELECT 1 FROM reviews WHERE review_id=$1 AND recipe_id=$4 AND "+reviewRecipeExists+") "+
+			"ON CONFLICT DO NOTHING",
+		rv.ID, username, now(), rv.RecipeID); err != nil {
+		return s.mapError(err)
+	}
+	return s.GetReview(rv)
+}
+
+// SetReviewHidden hides (or unhides) a specific review, as rv.Hidden says,
+// populating the review.
+func (s *SQLStore) SetReviewHidden(rv *Review) error {
+	res, err := s.DB.Exec("UPDATE reviews SET hidden=$1 WHERE review_id=$2 AND recipe_id=$3 AND "+reviewRecipeExists,
+		rv.Hidden, rv.ID, rv.RecipeID)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(res, ErrReviewNotFound); err != nil {
+		return err
+	}
+	return s.GetReview(rv)
+}
+
+// findReview returns the index of the specified review among those of its
+// recipe, or -1 (the caller holds the lock).
+func (s *MemoryStore) findReview(rv *Review) int {
+	if _, ok := s.recipes[rv.RecipeID]; !ok {
+		return -1
+	}
+	for n, existing := range s.reviews[rv.RecipeID] {
+		if existing.ID == rv.ID {
+			return n
+		}
+	}
+	return -1
+}
+
+// review returns a stored review, with its flags counted (the caller holds
+// the lock).
+func (s *MemoryStore) review(recipeID, n int) Review {
+	rv := s.reviews[recipeID][n]
+	rv.FlagCount = len(s.reviewFlags[rv.ID])
+	return rv
+}
+
+// GetReviews returns a page of (at most count of) the reviews of a specific
+// recipe, newest first, following the first start of them. Hidden reviews
+// are only included if withHidden.
+func (s *MemoryStore) GetReviews(recipeID, start, count int, withHidden bool) (ReviewPage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	page := ReviewPage{Reviews: []Review{}}
+	if _, ok := s.recipes[recipeID]; !ok {
+		return page, ErrRecipeNotFound
+	}
+	reviews := s.reviews[recipeID]
+	for n := len(reviews) - 1; n >= 0; n-- {
+		if reviews[n].Hidden && !withHidden {
+			continue
+		}
+		if page.Total >= start && len(page.Reviews) < count {
+			page.Reviews = append(page.Reviews, s.review(recipeID, n))
+		}
+		page.Total++
+	}
+	return page, nil
+}
+
+// GetReview populates the specified review (by ID and recipe ID).
+func (s *MemoryStore) GetReview(rv *Review) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.findReview(rv)
+	if n < 0 {
+		return ErrReviewNotFound
+	}
+	*rv = s.review(rv.RecipeID, n)
+	return nil
+}
+
+// AddReview adds a review of a specific recipe.
+func (s *MemoryStore) AddReview(rv *Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[rv.RecipeID]; !ok {
+		return ErrRecipeNotFound
+	}
+	if err := checkReview(rv); err != nil {
+		return err
+	}
+	rv.ID = s.nextReviewID
+	s.nextReviewID++
+	rv.CreatedAt = now()
+	rv.UpdatedAt, rv.FlagCount, rv.Hidden = rv.CreatedAt, 0, false
+	s.reviews[rv.RecipeID] = append(s.reviews[rv.RecipeID], *rv)
+	return nil
+}
+
+// UpdateReview is used to modify the rating and text of a specific review.
+func (s *MemoryStore) UpdateReview(rv *Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findReview(rv)
+	if n < 0 {
+		return ErrReviewNotFound
+	}
+	if err := checkReview(rv); err != nil {
+		return err
+	}
+	existing := &s.reviews[rv.RecipeID][n]
+	existing.Rating, existing.Text, existing.UpdatedAt = rv.Rating, rv.Text, now()
+	*rv = s.review(rv.RecipeID, n)
+	return nil
+}
+
+// DeleteReview is used to remove a specific review.
+func (s *MemoryStore) DeleteReview(rv *Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findReview(rv)
+	if n < 0 {
+		return ErrReviewNotFound
+	}
+	reviews := s.reviews[rv.RecipeID]
+	s.reviews[rv.RecipeID] = append(reviews[:n:n], reviews[n+1:]...)
+	delete(s.reviewFlags, rv.ID)
+	return nil
+}
+
+// FlagReview records that a user has flagged a specific review (which
+// counts once, however often they do so), populating the review.
+func (s *MemoryStore) FlagReview(rv *Review, username string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findReview(rv)
+	if n < 0 {
+		return ErrReviewNotFound
+	}
+	flagged := false
+	for _, flagger := range s.reviewFlags[rv.ID] {
+		flagged = flagged || flagger == username
+	}
+	if !flagged {
+		s.reviewFlags[rv.ID] = append(s.reviewFlags[rv.ID], username)
+	}
+	*rv = s.review(rv.RecipeID, n)
+	return nil
+}
+
+// SetReviewHidden hides (or unhides) a specific review, as rv.Hidden says,
+// populating the review.
+func (s *MemoryStore) SetReviewHidden(rv *Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findReview(rv)
+	if n < 0 {
+		return ErrReviewNotFound
+	}
+	s.reviews[rv.RecipeID][n].Hidden = rv.Hidden
+	*rv = s.review(rv.RecipeID, n)
+	return nil
+}
@@ -0,0 +1,161 @@
+package recipes
+
+import (
+	"database/sql"
+	"encoding/json"
+)
+
+// revisionColumns are the columns selected for each revision, in the order
+// scanned by scanRevision.
+const revisionColumns = "rv.recipe_id, rv.revision, rv.version, rv.name, rv.preptime, rv.difficulty, rv.vegetarian, " +
+	"rv.servings, r.created_at, r.owner, rv.author, rv.changes, rv.created_at " +
+	"FROM recipe_revisions rv JOIN recipes r ON r.id = rv.recipe_id"
+
+// revisedFields returns the fields of a recipe which are revised, keyed by
+// their JSON names.
+func revisedFields(r *Recipe) map[string]interface{} {
+	return map[string]interface{}{
+		"name":       r.Name,
+		"preptime":   r.PrepTime,
+		"difficulty": r.Difficulty,
+		"vegetarian": r.Vegetarian,
+		"servings":   r.Servings,
+	}
+}
+
+// diffRecipes returns the fields changed between two versions of a recipe
+// (every field, if there was no previous version).
+func diffRecipes(previous, r *Recipe) map[string]Change {
+	changes := map[string]Change{}
+	if previous == nil {
+		for name, value := range revisedFields(r) {
+			changes[name] = Change{To: value}
+		}
+		return changes
+	}
+	from := revisedFields(previous)
+	for name, value := range revisedFields(r) {
+		if from[name] != value {
+			changes[name] = Change{From: from[name], To: value}
+		}
+	}
+	return changes
+}
+
+// addRevision records a revision of a recipe (as it has just been left by
+// the changes), numbered after the last.
+func addRevision(e execer, r *Recipe, changes map[string]Change) error {
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+	_, err = e.Exec(
+		"INSERT INTO recipe_revisions(recipe_id, revision, version, name, preptime, difficulty, vegetarian, servings, "+
+			"author, changes, created_at) "+
+			"VALUES($1, (SELECT COALESCE(MAX(revision), 0) + 1 FROM recipe_revisions WHERE recipe_id=$1), "+
+			"$2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		r.ID, r.Version, r.Name, r.PrepTime, r.Difficulty, r.Vegetarian, r.Servings, r.ModifiedBy, string(b), now())
+	return err
+}
+
+// scanRevision reads a revision selected by revisionColumns.
+func scanRevision(scan func(dest ...interface{}) error, rev *Revision) error {
+	var changes string
+	if err := scan(&rev.RecipeID, &rev.Revision, &rev.Recipe.Version, &rev.Recipe.Name, &rev.Recipe.PrepTime,
+		&rev.Recipe.Difficulty, &rev.Recipe.Vegetarian, &rev.Recipe.Servings, &rev.Recipe.CreatedAt,
+		&rev.Recipe.Owner, &rev.Author, &changes, &rev.CreatedAt); err != nil {
+		return err
+	}
+	rev.Recipe.ID = rev.RecipeID
+	return json.Unmarshal([]byte(changes), &rev.Changes)
+}
+
+// GetRevisions returns the revisions of a specific recipe, newest first.
+func (s *SQLStore) GetRevisions(recipeID int) ([]Revision, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+
+	rows, err := s.DB.Query("SELECT "+revisionColumns+" WHERE rv.recipe_id=$1 ORDER BY rv.revision DESC", recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	revisions := []Revision{}
+	for rows.Next() {
+		var rev Revision
+		if err := scanRevision(rows.Scan, &rev); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+// GetRevision populates the specified revision (by number and recipe ID).
+func (s *SQLStore) GetRevision(rev *Revision) error {
+	err := scanRevision(s.DB.QueryRow(
+		"SELECT "+revisionColumns+" WHERE rv.recipe_id=$1 AND rv.revision=$2 AND r.deleted_at IS NULL",
+		rev.RecipeID, rev.Revision).Scan, rev)
+	if err != sql.ErrNoRows {
+		return err
+	}
+	exists, err := s.recipeExists(rev.RecipeID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRecipeNotFound
+	}
+	return ErrRevisionNotFound
+}
+
+// addRevision records a revision of a recipe (as it has just been left by
+// the changes), numbered after the last (the caller holds the lock).
+func (s *MemoryStore) addRevision(r *Recipe, changes map[string]Change) {
+	recipe := *r
+	recipe.ModifiedBy = ""
+	s.revisions[r.ID] = append(s.revisions[r.ID], Revision{
+		RecipeID:  r.ID,
+		Revision:  len(s.revisions[r.ID]) + 1,
+		Recipe:    recipe,
+		Author:    r.ModifiedBy,
+		Changes:   changes,
+		CreatedAt: now(),
+	})
+}
+
+// GetRevisions returns the revisions of a specific recipe, newest first.
+func (s *MemoryStore) GetRevisions(recipeID int) ([]Revision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	revisions := []Revision{}
+	for i := len(s.revisions[recipeID]) - 1; i >= 0; i-- {
+		revisions = append(revisions, s.revisions[recipeID][i])
+	}
+	return revisions, nil
+}
+
+// GetRevision populates the specified revision (by number and recipe ID).
+func (s *MemoryStore) GetRevision(rev *Revision) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[rev.RecipeID]; !ok {
+		return ErrRecipeNotFound
+	}
+	revisions := s.revisions[rev.RecipeID]
+	if rev.Revision < 1 || rev.Revision > len(revisions) {
+		return ErrRevisionNotFound
+	}
+	*rev = revisions[rev.Revision-1]
+	return nil
+}
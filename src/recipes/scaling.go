@@ -0,0 +1,50 @@
+package recipes
+
+import "math"
+
+// ScaleIngredients returns copies of the ingredients with their quantities
+// scaled from one number of servings to another (and sensibly rounded).
+func ScaleIngredients(ingredients []Ingredient, from int, to int) []Ingredient {
+	scaled := make([]Ingredient, 0, len(ingredients))
+	for _, i := range ingredients {
+		if from != to {
+			i.Quantity = RoundQuantity(i.Quantity*float64(to)/float64(from), i.Unit)
+		}
+		scaled = append(scaled, i)
+	}
+	return scaled
+}
+
+// RoundQuantity rounds a quantity to a precision which suits its unit:
+// US customary units (cups, spoons, ounces, etc) are rounded to the nearest
+// eighth, metric units to three significant figures, and everything else
+// (eggs, cloves, pinches) to the nearest quarter. A non-zero quantity is
+// never rounded away to nothing.
+func RoundQuantity(quantity float64, unit string) float64 {
+	if quantity <= 0 {
+		return quantity
+	}
+	canonical, known := canonicalUnit(unit)
+	switch {
+	case known && isMetric(canonical):
+		return roundSignificant(quantity, 3)
+	case known:
+		return roundToStep(quantity, 0.125)
+	}
+	return roundToStep(quantity, 0.25)
+}
+
+// roundToStep rounds a quantity to the nearest multiple of step (but never to zero).
+func roundToStep(quantity float64, step float64) float64 {
+	rounded := math.Round(quantity/step) * step
+	if rounded == 0 {
+		return step
+	}
+	return rounded
+}
+
+// roundSignificant rounds a positive quantity to the specified number of significant figures.
+func roundSignificant(quantity float64, digits int) float64 {
+	scale := math.Pow(10, float64(digits)-math.Ceil(math.Log10(quantity)))
+	return math.Round(quantity*scale) / scale
+}
@@ -0,0 +1,125 @@
+package recipes
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The weights given to matching words in each part of a recipe (the same
+// as the Postgres ts_rank defaults for weights A, B and C).
+const (
+	nameWeight       = 1.0
+	ingredientWeight = 0.4
+	stepWeight       = 0.2
+)
+
+// stopWords are too common to be worth searching for.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true, "by": true,
+	"for": true, "from": true, "in": true, "into": true, "is": true, "it": true, "of": true, "on": true,
+	"or": true, "the": true, "then": true, "to": true, "until": true, "with": true,
+}
+
+// tokenize splits text into lower case, stemmed words, dropping stop words.
+func tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	tokens := []string{}
+	for _, word := range words {
+		if !stopWords[word] {
+			tokens = append(tokens, stem(word))
+		}
+	}
+	return tokens
+}
+
+// stem strips the common English suffixes, so that (for instance) "baked",
+// "bakes" and "baking" all match "bake".
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		word = word[:len(word)-3] + "y"
+	case len(word) > 4 && (strings.HasSuffix(word, "oes") || strings.HasSuffix(word, "ches") ||
+		strings.HasSuffix(word, "shes") || strings.HasSuffix(word, "sses") || strings.HasSuffix(word, "xes")):
+		word = word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us"):
+		word = word[:len(word)-1]
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		word = word[:len(word)-3]
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		word = word[:len(word)-2]
+	}
+	if len(word) > 3 && strings.HasSuffix(word, "e") {
+		word = word[:len(word)-1]
+	}
+	return word
+}
+
+// Relevance scores how well a recipe (its name, ingredients and steps)
+// matches the query text, between 0 and 1. A recipe which does not contain
+// every word of the query scores 0.
+func Relevance(query, name, ingredients, steps string) float64 {
+	terms := tokenize(query)
+	if len(terms) == 0 {
+		return 0
+	}
+	fields := []struct {
+		weight float64
+		tokens []string
+	}{
+		{nameWeight, tokenize(name)},
+		{ingredientWeight, tokenize(ingredients)},
+		{stepWeight, tokenize(steps)},
+	}
+	var score float64
+	for _, term := range terms {
+		var termScore float64
+		for _, field := range fields {
+			for _, token := range field.tokens {
+				if token == term {
+					termScore += field.weight
+				}
+			}
+		}
+		if termScore == 0 {
+			return 0
+		}
+		score += termScore
+	}
+	// squash the (unbounded) score into the range of ts_rank
+	return 1 - 1/(1+score/float64(len(terms)))
+}
+
+// postgresDocument is the full-text search document of a recipe, weighting
+// its name above its ingredients, and those above its steps.
+const postgresDocument = "(setweight(to_tsvector('english', recipes.name), 'A') || " +
+	"setweight(to_tsvector('english', COALESCE((SELECT string_agg(item || ' ' || note, ' ') FROM ingredients " +
+	"WHERE ingredients.recipe_id = recipes.id), '')), 'B') || " +
+	"setweight(to_tsvector('english', COALESCE((SELECT string_agg(text, ' ') FROM steps " +
+	"WHERE steps.recipe_id = recipes.id), '')), 'C'))"
+
+// relevance returns the SQL expression which scores how well each recipe
+// matches the query text (bound to the placeholder).
+func (s *SQLStore) relevance(placeholder string) string {
+	if s.Driver == SQLite {
+		// see Relevance, which is registered along with the driver
+		return "recipe_relevance(" + placeholder + ", recipes.name, " +
+			"COALESCE((SELECT group_concat(item || ' ' || note, ' ') FROM ingredients WHERE ingredients.recipe_id = recipes.id), ''), " +
+			"COALESCE((SELECT group_concat(text, ' ') FROM steps WHERE steps.recipe_id = recipes.id), ''))"
+	}
+	return "ts_rank(" + postgresDocument + ", plainto_tsquery('english', " + placeholder + "))"
+}
+
+// relevance scores how well a recipe matches the query text (the caller holds the lock).
+func (s *MemoryStore) relevance(recipeID int, text string) float64 {
+	ingredients := []string{}
+	for _, i := range s.ingredients[recipeID] {
+		ingredients = append(ingredients, i.Item, i.Note)
+	}
+	steps := []string{}
+	for _, st := range s.steps[recipeID] {
+		steps = append(steps, st.Text)
+	}
+	return Relevance(text, s.recipes[recipeID].Name, strings.Join(ingredients, " "), strings.Join(steps, " "))
+}
@@ -0,0 +1,175 @@
+package recipes
+
+import (
+	"database/sql"
+	"time"
+
+	// GitHub packages
+	"github.com/jmoiron/sqlx"
+)
+
+// The supported SQL drivers.
+const (
+	Postgres = "postgres"
+	SQLite   = "sqlite3"
+)
+
+// SQLStore is a RecipeStore backed by an SQL database (PostgreSQL or SQLite).
+type SQLStore struct {
+	DB     *sqlx.DB
+	Driver string
+}
+
+// mapError translates driver-specific constraint violations into store errors.
+func (s *SQLStore) mapError(err error) error {
+	if s.Driver == SQLite {
+		return mapSQLiteError(err)
+	}
+	return mapPostgresError(err)
+}
+
+// mapDuplicateError translates driver-specific constraint violations into
+// store errors, reporting a unique violation as duplicate.
+func (s *SQLStore) mapDuplicateError(err, duplicate error) error {
+	err = s.mapError(err)
+	if err == ErrUniqueViolation {
+		return duplicate
+	}
+	return err
+}
+
+// now returns the current time, as it is stored (to the microsecond, in UTC).
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// execer is implemented by both the database and its transactions.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// touchRecipe advances the version of a recipe, when something shown along
+// with it (an ingredient, step, tag or term) changes.
+func touchRecipe(e execer, recipeID int) error {
+	_, err := e.Exec("UPDATE recipes SET version = version + 1 WHERE id=$1", recipeID)
+	return err
+}
+
+// missingRecipe explains why a recipe was not updated or deleted: either it
+// does not exist, or it is no longer the version expected.
+func (s *SQLStore) missingRecipe(recipeID int) error {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrRecipeNotFound
+}
+
+// GetRecipe returns a single specified recipe.
+func (s *SQLStore) GetRecipe(r *Recipe) error {
+	err := s.DB.QueryRow("SELECT name, preptime, difficulty, vegetarian, servings, created_at, version, owner FROM recipes WHERE id=$1 AND deleted_at IS NULL",
+		r.ID).Scan(&r.Name, &r.PrepTime, &r.Difficulty, &r.Vegetarian, &r.Servings, &r.CreatedAt, &r.Version, &r.Owner)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	return err
+}
+
+// UpdateRecipe is used to modify a specific recipe, advancing its version
+// and recording a revision. If a version is specified, the recipe is only
+// modified if it is still that version (and ErrVersionConflict is returned if not).
+func (s *SQLStore) UpdateRecipe(r *Recipe) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// the recipe is locked until the revision is recorded (SQLite only ever
+	// allows a single writer, so no lock is needed)
+	query := "SELECT name, preptime, difficulty, vegetarian, servings, version FROM recipes WHERE id=$1 AND deleted_at IS NULL"
+	if s.Driver == Postgres {
+		query += " FOR UPDATE"
+	}
+	existing := Recipe{ID: r.ID}
+	err = tx.QueryRow(query, r.ID).Scan(
+		&existing.Name, &existing.PrepTime, &existing.Difficulty, &existing.Vegetarian, &existing.Servings, &existing.Version)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if r.Version != 0 && r.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	err = tx.QueryRow(
+		"UPDATE recipes SET name=$1, preptime=$2, difficulty=$3, vegetarian=$4, servings=$5, version = version + 1 "+
+			"WHERE id=$6 RETURNING created_at, version, owner",
+		r.Name, r.PrepTime, r.Difficulty, r.Vegetarian, r.Servings, r.ID).Scan(&r.CreatedAt, &r.Version, &r.Owner)
+	if err != nil {
+		return s.mapDuplicateError(err, ErrDuplicateRecipe)
+	}
+	if err := addRevision(tx, r, diffRecipes(&existing, r)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteRecipe is used to move a specific recipe to the trash (from which
+// it may be restored until it is purged). If a version is specified, the
+// recipe is only deleted if it is still that version (and
+// ErrVersionConflict is returned if not).
+func (s *SQLStore) DeleteRecipe(r *Recipe) error {
+	query := "UPDATE recipes SET deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL"
+	args := []interface{}{now(), r.ID}
+	if r.Version != 0 {
+		query += " AND version=$3"
+		args = append(args, r.Version)
+	}
+	res, err := s.DB.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(res, ErrRecipeNotFound); err != ErrRecipeNotFound {
+		return err
+	}
+	return s.missingRecipe(r.ID)
+}
+
+// CreateRecipe is used to create a single recipe, recording its first revision.
+func (s *SQLStore) CreateRecipe(r *Recipe) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	r.CreatedAt = now()
+	err = tx.QueryRow(
+		"INSERT INTO recipes(name, preptime, difficulty, vegetarian, servings, created_at, owner) VALUES($1, $2, $3, $4, $5, $6, $7) "+
+			"RETURNING id, version",
+		r.Name, r.PrepTime, r.Difficulty, r.Vegetarian, r.Servings, r.CreatedAt, r.Owner).Scan(&r.ID, &r.Version)
+	if err != nil {
+		return s.mapDuplicateError(err, ErrDuplicateRecipe)
+	}
+	if err := addRevision(tx, r, diffRecipes(nil, r)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkRowsAffected maps an UPDATE or DELETE that matched nothing to notFound.
+func checkRowsAffected(res sql.Result, notFound error) error {
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return notFound
+	}
+	return nil
+}
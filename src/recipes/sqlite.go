@@ -0,0 +1,58 @@
+package recipes
+
+import (
+	"database/sql"
+
+	// GitHub packages
+	"github.com/jmoiron/sqlx"
+	"github.com/mattn/go-sqlite3"
+)
+
+// SQLiteDriver is the name of the SQLite driver registered by this package,
+// which adds the SQL functions the SQLStore relies upon.
+const SQLiteDriver = "sqlite3_recipes"
+
+func init() {
+	sql.Register(SQLiteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			// SQLite has no full-text ranking of its own (short of FTS5)
+			if err := conn.RegisterFunc("recipe_relevance", Relevance, true); err != nil {
+				return err
+			}
+			// nor any fuzzy string matching
+			return conn.RegisterFunc("recipe_similarity", Similarity, true)
+		},
+	})
+}
+
+// SQLiteDataSource returns the data source name for the specified database
+// file, with foreign keys (and therefore cascading deletes) enabled.
+func SQLiteDataSource(file string) string {
+	return "file:" + file + "?_foreign_keys=on&_busy_timeout=5000"
+}
+
+// NewSQLiteStore returns an SQLStore using the supplied SQLite connection
+// (which must have been opened with the SQLiteDriver).
+// SQLite only allows a single writer, so the pool is limited to a single
+// connection (this also keeps ":memory:" databases from being duplicated).
+func NewSQLiteStore(db *sqlx.DB) *SQLStore {
+	db.SetMaxOpenConns(1)
+	return &SQLStore{DB: db, Driver: SQLite}
+}
+
+// mapSQLiteError translates SQLite constraint violations into store errors.
+func mapSQLiteError(err error) error {
+	sqliteErr, ok := err.(sqlite3.Error)
+	if !ok {
+		return err
+	}
+	switch sqliteErr.ExtendedCode {
+	case sqlite3.ErrConstraintUnique, sqlite3.ErrConstraintPrimaryKey:
+		return ErrUniqueViolation
+	case sqlite3.ErrConstraintCheck:
+		return ErrCheckViolation
+	case sqlite3.ErrConstraintForeignKey:
+		return ErrReferenceViolation
+	}
+	return err
+}
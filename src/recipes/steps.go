@@ -0,0 +1,337 @@
+package recipes
+
+import "database/sql"
+
+// GetSteps returns the steps of a specific recipe, in order.
+func (s *SQLStore) GetSteps(recipeID int) ([]Step, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+	return querySteps(s.DB, recipeID)
+}
+
+// queryer is satisfied by both *sqlx.DB and *sql.Tx.
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// querySteps returns the steps of a specific recipe, in order.
+func querySteps(q queryer, recipeID int) ([]Step, error) {
+	rows, err := q.Query(
+		"SELECT step_id, recipe_id, position, text, duration, timer FROM steps WHERE recipe_id=$1 ORDER BY position, step_id",
+		recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	steps := []Step{}
+	for rows.Next() {
+		var st Step
+		if err := rows.Scan(&st.ID, &st.RecipeID, &st.Position, &st.Text, &st.Duration, &st.Timer); err != nil {
+			return nil, err
+		}
+		steps = append(steps, st)
+	}
+
+	return steps, rows.Err()
+}
+
+// GetStep returns a single specified step.
+func (s *SQLStore) GetStep(st *Step) error {
+	err := s.DB.QueryRow(
+		"SELECT position, text, duration, timer FROM steps WHERE step_id=$1 AND recipe_id=$2 AND "+notInTrash,
+		st.ID, st.RecipeID).Scan(&st.Position, &st.Text, &st.Duration, &st.Timer)
+	if err == sql.ErrNoRows {
+		return ErrStepNotFound
+	}
+	return err
+}
+
+// lockSteps locks a recipe (which must not be in the trash) until the end of
+// the transaction, so that its steps may be numbered without a concurrent
+// change (SQLite only ever allows a single writer, so no lock is needed).
+func (s *SQLStore) lockSteps(tx *sql.Tx, recipeID int) error {
+	query := "SELECT id FROM recipes WHERE id=$1 AND deleted_at IS NULL"
+	if s.Driver == Postgres {
+		query += " FOR UPDATE"
+	}
+	err := tx.QueryRow(query, recipeID).Scan(&recipeID)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	return err
+}
+
+// AddStep appends a step to a specific recipe.
+func (s *SQLStore) AddStep(st *Step) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.lockSteps(tx, st.RecipeID); err != nil {
+		return err
+	}
+	err = tx.QueryRow(
+		"INSERT INTO steps(recipe_id, position, text, duration, timer) "+
+			"VALUES($1, (SELECT COALESCE(MAX(position), 0) + 1 FROM steps WHERE recipe_id=$1), $2, $3, $4) "+
+			"RETURNING step_id, position",
+		st.RecipeID, st.Text, st.Duration, st.Timer).Scan(&st.ID, &st.Position)
+	if err != nil {
+		return s.mapDuplicateError(err, ErrDuplicateStep)
+	}
+	if err := touchRecipe(tx, st.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateStep is used to modify a specific step (but not its position).
+func (s *SQLStore) UpdateStep(st *Step) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRow(
+		"UPDATE steps SET text=$1, duration=$2, timer=$3 WHERE step_id=$4 AND recipe_id=$5 AND "+notInTrash+" RETURNING position",
+		st.Text, st.Duration, st.Timer, st.ID, st.RecipeID).Scan(&st.Position)
+	if err == sql.ErrNoRows {
+		return ErrStepNotFound
+	}
+	if err != nil {
+		return s.mapError(err)
+	}
+	if err := touchRecipe(tx, st.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteStep is used to remove a specific step, closing up the gap.
+func (s *SQLStore) DeleteStep(st *Step) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.lockSteps(tx, st.RecipeID); err == ErrRecipeNotFound {
+		return ErrStepNotFound
+	} else if err != nil {
+		return err
+	}
+	err = tx.QueryRow("DELETE FROM steps WHERE step_id=$1 AND recipe_id=$2 RETURNING position",
+		st.ID, st.RecipeID).Scan(&st.Position)
+	if err == sql.ErrNoRows {
+		return ErrStepNotFound
+	}
+	if err != nil {
+		return err
+	}
+	// positions are unique (and may be checked row by row), so the steps
+	// which follow are moved past the last before being moved up
+	var last int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(position), 0) FROM steps WHERE recipe_id=$1", st.RecipeID).Scan(&last); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE steps SET position = position + $1 WHERE recipe_id=$2 AND position > $3",
+		last, st.RecipeID, st.Position); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE steps SET position = position - $1 - 1 WHERE recipe_id=$2 AND position > $1",
+		last, st.RecipeID); err != nil {
+		return err
+	}
+	if err := touchRecipe(tx, st.RecipeID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ReorderSteps rearranges the steps of a recipe into the order given.
+func (s *SQLStore) ReorderSteps(recipeID int, stepIDs []int) ([]Step, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.lockSteps(tx, recipeID); err != nil {
+		return nil, err
+	}
+	current, err := querySteps(tx, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !isPermutation(current, stepIDs) {
+		return nil, ErrInvalidStepOrder
+	}
+	// positions are unique, so the steps are moved past the last first
+	if _, err := tx.Exec("UPDATE steps SET position = position + $1 WHERE recipe_id=$2", len(current), recipeID); err != nil {
+		return nil, err
+	}
+	for n, id := range stepIDs {
+		if _, err := tx.Exec("UPDATE steps SET position=$1 WHERE step_id=$2", n+1, id); err != nil {
+			return nil, err
+		}
+	}
+	if err := touchRecipe(tx, recipeID); err != nil {
+		return nil, err
+	}
+	steps, err := querySteps(tx, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	return steps, tx.Commit()
+}
+
+// isPermutation reports whether stepIDs lists every one of the steps exactly once.
+func isPermutation(steps []Step, stepIDs []int) bool {
+	if len(steps) != len(stepIDs) {
+		return false
+	}
+	seen := map[int]bool{}
+	for _, st := range steps {
+		seen[st.ID] = false
+	}
+	for _, id := range stepIDs {
+		used, ok := seen[id]
+		if !ok || used {
+			return false
+		}
+		seen[id] = true
+	}
+	return true
+}
+
+// checkStep enforces the steps table constraints.
+func checkStep(st *Step) error {
+	if st.Text == "" || st.Duration < 0 || st.Timer < 0 {
+		return ErrCheckViolation
+	}
+	return nil
+}
+
+// findStep returns the index of the specified step within its recipe,
+// or -1 if it does not exist (the caller holds the lock).
+func (s *MemoryStore) findStep(st *Step) int {
+	if _, ok := s.recipes[st.RecipeID]; !ok {
+		return -1
+	}
+	for n, existing := range s.steps[st.RecipeID] {
+		if existing.ID == st.ID {
+			return n
+		}
+	}
+	return -1
+}
+
+// renumberSteps sets the positions of a recipe's steps from their order
+// (the caller holds the lock).
+func (s *MemoryStore) renumberSteps(recipeID int) {
+	for n := range s.steps[recipeID] {
+		s.steps[recipeID][n].Position = n + 1
+	}
+}
+
+// GetSteps returns the steps of a specific recipe, in order.
+func (s *MemoryStore) GetSteps(recipeID int) ([]Step, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	return append([]Step{}, s.steps[recipeID]...), nil
+}
+
+// GetStep returns a single specified step.
+func (s *MemoryStore) GetStep(st *Step) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := s.findStep(st)
+	if n < 0 {
+		return ErrStepNotFound
+	}
+	*st = s.steps[st.RecipeID][n]
+	return nil
+}
+
+// AddStep appends a step to a specific recipe.
+func (s *MemoryStore) AddStep(st *Step) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[st.RecipeID]; !ok {
+		return ErrRecipeNotFound
+	}
+	if err := checkStep(st); err != nil {
+		return err
+	}
+	st.ID = s.nextStepID
+	s.nextStepID++
+	st.Position = len(s.steps[st.RecipeID]) + 1
+	s.steps[st.RecipeID] = append(s.steps[st.RecipeID], *st)
+	s.touch(st.RecipeID)
+	return nil
+}
+
+// UpdateStep is used to modify a specific step (but not its position).
+func (s *MemoryStore) UpdateStep(st *Step) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findStep(st)
+	if n < 0 {
+		return ErrStepNotFound
+	}
+	if err := checkStep(st); err != nil {
+		return err
+	}
+	st.Position = s.steps[st.RecipeID][n].Position
+	s.steps[st.RecipeID][n] = *st
+	s.touch(st.RecipeID)
+	return nil
+}
+
+// DeleteStep is used to remove a specific step, closing up the gap.
+func (s *MemoryStore) DeleteStep(st *Step) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := s.findStep(st)
+	if n < 0 {
+		return ErrStepNotFound
+	}
+	steps := s.steps[st.RecipeID]
+	s.steps[st.RecipeID] = append(steps[:n:n], steps[n+1:]...)
+	s.renumberSteps(st.RecipeID)
+	s.touch(st.RecipeID)
+	return nil
+}
+
+// ReorderSteps rearranges the steps of a recipe into the order given.
+func (s *MemoryStore) ReorderSteps(recipeID int, stepIDs []int) ([]Step, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	current := s.steps[recipeID]
+	if !isPermutation(current, stepIDs) {
+		return nil, ErrInvalidStepOrder
+	}
+	byID := map[int]Step{}
+	for _, st := range current {
+		byID[st.ID] = st
+	}
+	reordered := make([]Step, 0, len(stepIDs))
+	for _, id := range stepIDs {
+		reordered = append(reordered, byID[id])
+	}
+	s.steps[recipeID] = reordered
+	s.renumberSteps(recipeID)
+	s.touch(recipeID)
+	return append([]Step{}, reordered...), nil
+}
@@ -0,0 +1,244 @@
+// Package recipes is the data access layer for recipes, their ingredients,
+// steps, ratings, reviews, tags and taxonomy terms.
+package recipes
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRecipeNotFound is returned when the specified recipe does not exist.
+var ErrRecipeNotFound = errors.New("recipe not found")
+
+// ErrIngredientNotFound is returned when the specified ingredient does not exist.
+var ErrIngredientNotFound = errors.New("ingredient not found")
+
+// ErrStepNotFound is returned when the specified step does not exist.
+var ErrStepNotFound = errors.New("step not found")
+
+// ErrRevisionNotFound is returned when the specified revision does not exist.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ErrInvalidStepOrder is returned when a new step order does not list every step of the recipe exactly once.
+var ErrInvalidStepOrder = errors.New("step order must list every step of the recipe exactly once")
+
+// ErrVersionConflict is returned when a recipe is no longer the version expected.
+var ErrVersionConflict = errors.New("recipe has been modified")
+
+// ErrUniqueViolation is returned when a value must be unique but is already in use.
+var ErrUniqueViolation = errors.New("value already in use")
+
+// ErrDuplicateRecipe is returned when a recipe name is already in use.
+var ErrDuplicateRecipe = errors.New("recipe name already exists")
+
+// ErrCheckViolation is returned when a value is outside of its permitted range.
+var ErrCheckViolation = errors.New("value out of range")
+
+// ErrReferenceViolation is returned when a value refers to a record which does not exist.
+var ErrReferenceViolation = errors.New("referenced record does not exist")
+
+// ErrInvalidSort is returned when recipes cannot be sorted by the field requested.
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// ErrInvalidCursor is returned when a page cursor cannot be parsed.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrTermNotFound is returned when the specified taxonomy term does not exist.
+var ErrTermNotFound = errors.New("term not found")
+
+// ErrDuplicateTerm is returned when a term name is already in use within its taxonomy.
+var ErrDuplicateTerm = errors.New("term already exists")
+
+// ErrRatingNotFound is returned when the specified rating does not exist.
+var ErrRatingNotFound = errors.New("rating not found")
+
+// ErrReviewNotFound is returned when the specified review does not exist.
+var ErrReviewNotFound = errors.New("review not found")
+
+// ErrUserNotFound is returned when the specified user does not exist.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrDuplicateUser is returned when a username is already in use.
+var ErrDuplicateUser = errors.New("username already exists")
+
+// ErrDuplicateStep is returned when the position of a step is already in use.
+var ErrDuplicateStep = errors.New("step position already in use")
+
+// ErrDuplicateAPIKey is returned when the hash of an API key is already in use.
+var ErrDuplicateAPIKey = errors.New("API key already exists")
+
+// ErrAPIKeyNotFound is returned when the specified API key does not exist.
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+// Store is the complete set of storage operations used by the application.
+type Store interface {
+	RecipeStore
+	RatingStore
+	ReviewStore
+	IngredientStore
+	StepStore
+	TagStore
+	TaxonomyStore
+	RevisionStore
+	TrashStore
+	UserStore
+	APIKeyStore
+}
+
+// RecipeStore is implemented by each of the storage back-ends.
+type RecipeStore interface {
+	// GetRecipe populates the specified recipe (by ID).
+	GetRecipe(r *Recipe) error
+	// UpdateRecipe is used to modify a specific recipe, advancing its version
+	// and recording a revision. If a version is specified, the recipe is only
+	// modified if it is still that version.
+	UpdateRecipe(r *Recipe) error
+	// DeleteRecipe is used to move a specific recipe to the trash. If a version
+	// is specified, the recipe is only deleted if it is still that version.
+	DeleteRecipe(r *Recipe) error
+	// CreateRecipe is used to create a single recipe, recording its first revision.
+	CreateRecipe(r *Recipe) error
+	// GetRecipesPage returns a page of the recipes which match the filter, in order.
+	GetRecipesPage(f RecipeFilter, o RecipeSort, p Page) (RecipePage, error)
+	// GetRecipeFacets counts the recipes which match the filter by each facet.
+	GetRecipeFacets(f RecipeFilter) (Facets, error)
+	// SuggestRecipes returns (at most count of) the recipes whose names most
+	// closely match the prefix, best first.
+	SuggestRecipes(prefix string, count int) ([]Suggestion, error)
+}
+
+// RatingStore is implemented by each of the storage back-ends.
+type RatingStore interface {
+	// GetRecipeRatings returns the ratings of a specific recipe, oldest first.
+	GetRecipeRatings(recipeID int) ([]RecipeRating, error)
+	// GetRecipeRating populates the rating of a specific recipe by a specific rater.
+	GetRecipeRating(rr *RecipeRating) error
+	// SetRecipeRating adds the rating of a specific recipe by a rater, replacing
+	// any rating they have already given it.
+	SetRecipeRating(rr *RecipeRating) error
+	// DeleteRecipeRating removes the rating of a specific recipe by a specific rater.
+	DeleteRecipeRating(rr *RecipeRating) error
+}
+
+// ReviewStore is implemented by each of the storage back-ends.
+type ReviewStore interface {
+	// GetReviews returns a page of (at most count of) the reviews of a specific
+	// recipe, newest first, following the first start of them. Hidden reviews
+	// are only included if withHidden.
+	GetReviews(recipeID, start, count int, withHidden bool) (ReviewPage, error)
+	// GetReview populates the specified review (by ID and recipe ID).
+	GetReview(rv *Review) error
+	// AddReview adds a review of a specific recipe.
+	AddReview(rv *Review) error
+	// UpdateReview is used to modify the rating and text of a specific review.
+	UpdateReview(rv *Review) error
+	// DeleteReview is used to remove a specific review.
+	DeleteReview(rv *Review) error
+	// FlagReview records that a user has flagged a specific review (which
+	// counts once, however often they do so), populating the review.
+	FlagReview(rv *Review, username string) error
+	// SetReviewHidden hides (or unhides) a specific review, as rv.Hidden says,
+	// populating the review.
+	SetReviewHidden(rv *Review) error
+}
+
+// IngredientStore is implemented by each of the storage back-ends.
+type IngredientStore interface {
+	// GetIngredients returns the ingredients of a specific recipe.
+	GetIngredients(recipeID int) ([]Ingredient, error)
+	// GetIngredient populates the specified ingredient (by ID and recipe ID).
+	GetIngredient(i *Ingredient) error
+	// AddIngredient adds an ingredient to a specific recipe.
+	AddIngredient(i *Ingredient) error
+	// UpdateIngredient is used to modify a specific ingredient.
+	UpdateIngredient(i *Ingredient) error
+	// DeleteIngredient is used to remove a specific ingredient.
+	DeleteIngredient(i *Ingredient) error
+}
+
+// StepStore is implemented by each of the storage back-ends.
+type StepStore interface {
+	// GetSteps returns the steps of a specific recipe, in order.
+	GetSteps(recipeID int) ([]Step, error)
+	// GetStep populates the specified step (by ID and recipe ID).
+	GetStep(st *Step) error
+	// AddStep appends a step to a specific recipe.
+	AddStep(st *Step) error
+	// UpdateStep is used to modify a specific step (but not its position).
+	UpdateStep(st *Step) error
+	// DeleteStep is used to remove a specific step, closing up the gap.
+	DeleteStep(st *Step) error
+	// ReorderSteps rearranges the steps of a recipe into the order given.
+	ReorderSteps(recipeID int, stepIDs []int) ([]Step, error)
+}
+
+// TagStore is implemented by each of the storage back-ends.
+type TagStore interface {
+	// GetTags returns every tag in use, most used first.
+	GetTags() ([]Tag, error)
+	// GetRecipeTags returns the tags of a specific recipe, in name order.
+	GetRecipeTags(recipeID int) ([]string, error)
+	// SetRecipeTags replaces the tags of a specific recipe, creating any new tags.
+	SetRecipeTags(recipeID int, tags []string) ([]string, error)
+}
+
+// TaxonomyStore is implemented by each of the storage back-ends.
+type TaxonomyStore interface {
+	// GetTerms returns the terms of a taxonomy, in name order.
+	GetTerms(taxonomy string) ([]Term, error)
+	// AddTerm adds a term to a taxonomy.
+	AddTerm(t *Term) error
+	// DeleteTerm removes a term from its taxonomy (and from every recipe).
+	DeleteTerm(t *Term) error
+	// GetRecipeTerms returns the terms of a taxonomy that a specific recipe is classified under.
+	GetRecipeTerms(recipeID int, taxonomy string) ([]string, error)
+	// SetRecipeTerms replaces the terms of a taxonomy that a specific recipe is
+	// classified under. Every term must already exist.
+	SetRecipeTerms(recipeID int, taxonomy string, terms []string) ([]string, error)
+}
+
+// RevisionStore is implemented by each of the storage back-ends.
+type RevisionStore interface {
+	// GetRevisions returns the revisions of a specific recipe, newest first.
+	GetRevisions(recipeID int) ([]Revision, error)
+	// GetRevision populates the specified revision (by number and recipe ID).
+	GetRevision(rev *Revision) error
+}
+
+// TrashStore is implemented by each of the storage back-ends. Recipes in the
+// trash are not found by any other operation.
+type TrashStore interface {
+	// GetDeletedRecipes returns (a page of) the recipes in the trash, most
+	// recently deleted first.
+	GetDeletedRecipes(start, count int) ([]Recipe, error)
+	// RestoreRecipe takes a specific recipe out of the trash, populating it.
+	RestoreRecipe(r *Recipe) error
+	// PurgeRecipe permanently deletes a specific recipe in the trash.
+	PurgeRecipe(r *Recipe) error
+	// PurgeRecipes permanently deletes the recipes which were moved to the
+	// trash before the time given, returning how many were purged.
+	PurgeRecipes(deletedBefore time.Time) (int, error)
+}
+
+// UserStore is implemented by each of the storage back-ends.
+type UserStore interface {
+	// CreateUser is used to create a single user account.
+	CreateUser(u *User) error
+	// GetUser populates the specified user (by username).
+	GetUser(u *User) error
+	// SetUserRole gives the specified user (by username) a role, populating them.
+	SetUserRole(u *User) error
+}
+
+// APIKeyStore is implemented by each of the storage back-ends.
+type APIKeyStore interface {
+	// GetAPIKeys returns every API key, in the order they were created.
+	GetAPIKeys() ([]APIKey, error)
+	// CreateAPIKey is used to create a single API key (from its hash).
+	CreateAPIKey(k *APIKey) error
+	// UseAPIKey populates the specified API key (by hash), recording that it
+	// has been used.
+	UseAPIKey(k *APIKey) error
+	// DeleteAPIKey is used to revoke a specific API key.
+	DeleteAPIKey(k *APIKey) error
+}
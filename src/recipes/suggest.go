@@ -0,0 +1,130 @@
+package recipes
+
+import "sort"
+
+// The least scores of the names which are suggested: in-process by
+// Similarity (allowing an edit for every 3 or so letters of the prefix), and
+// by pg_trgm (whose own default threshold is 0.3).
+const (
+	minSimilarity        = 0.7
+	minTrigramSimilarity = 0.3
+)
+
+// Similarity scores how closely a recipe name matches the prefix of one
+// typed so far, between 0 and 1. It is 1 if the name (or any word of it)
+// starts with the prefix, and falls with the number of edits (insertions,
+// deletions or substitutions) needed for it to do so, relative to the length
+// of the prefix. Case and spacing are ignored.
+func Similarity(prefix, name string) float64 {
+	p := []rune(normalizeName(prefix))
+	n := []rune(normalizeName(name))
+	if len(p) == 0 {
+		return 0
+	}
+	best := len(p)
+	for i := range n {
+		if i == 0 || n[i-1] == ' ' {
+			if d := prefixDistance(p, n[i:]); d < best {
+				best = d
+			}
+		}
+	}
+	return 1 - float64(best)/float64(len(p))
+}
+
+// prefixDistance returns the least edit (Levenshtein) distance between p
+// and any prefix of s.
+func prefixDistance(p, s []rune) int {
+	// row[j] is the distance between the runes of p so far and the first j of s
+	row := make([]int, len(s)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(p); i++ {
+		diagonal := row[0]
+		row[0] = i
+		for j := 1; j <= len(s); j++ {
+			substitution := diagonal
+			if p[i-1] != s[j-1] {
+				substitution++
+			}
+			diagonal = row[j]
+			row[j] = minInt(minInt(row[j]+1, row[j-1]+1), substitution)
+		}
+	}
+	best := row[0]
+	for _, d := range row {
+		best = minInt(best, d)
+	}
+	return best
+}
+
+// minInt returns the lesser of two ints.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// SuggestRecipes returns (at most count of) the recipes whose names most
+// closely match the prefix, best first.
+func (s *SQLStore) SuggestRecipes(prefix string, count int) ([]Suggestion, error) {
+	prefix = normalizeName(prefix)
+	q := &recipeQuery{}
+	var query string
+	if s.Driver == SQLite {
+		// see Similarity, which is registered along with the driver
+		query = "SELECT id, name, score FROM (SELECT id, name, recipe_similarity(" + q.bind(prefix) +
+			", name) AS score FROM recipes WHERE deleted_at IS NULL) AS suggested WHERE score >= " + q.bind(minSimilarity)
+	} else {
+		// trigrams of the start of the name (as long as the prefix) are compared
+		length := len([]rune(prefix))
+		query = "SELECT id, name, score FROM (SELECT id, name, CASE WHEN left(lower(name), " + q.bind(length) + ") = " +
+			q.bind(prefix) + " THEN 1 ELSE similarity(" + q.bind(prefix) + ", left(lower(name), " + q.bind(length) +
+			")) END AS score FROM recipes WHERE deleted_at IS NULL) AS suggested WHERE score >= " + q.bind(minTrigramSimilarity)
+	}
+	rows, err := s.DB.Query(query+" ORDER BY score DESC, name, id LIMIT "+q.bind(count), q.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	suggestions := []Suggestion{}
+	for rows.Next() {
+		var sg Suggestion
+		if err := rows.Scan(&sg.ID, &sg.Name, &sg.Score); err != nil {
+			return nil, err
+		}
+		suggestions = append(suggestions, sg)
+	}
+
+	return suggestions, rows.Err()
+}
+
+// SuggestRecipes returns (at most count of) the recipes whose names most
+// closely match the prefix, best first.
+func (s *MemoryStore) SuggestRecipes(prefix string, count int) ([]Suggestion, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	suggestions := []Suggestion{}
+	for id, r := range s.recipes {
+		if score := Similarity(prefix, r.Name); score >= minSimilarity {
+			suggestions = append(suggestions, Suggestion{ID: id, Name: r.Name, Score: score})
+		}
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	if len(suggestions) > count {
+		suggestions = suggestions[:count]
+	}
+	return suggestions, nil
+}
@@ -0,0 +1,190 @@
+package recipes
+
+import (
+	"sort"
+	"strings"
+)
+
+// maxNameLength is the longest permitted tag or term name.
+const maxNameLength = 50
+
+// normalizeName folds a tag or term name to lower case, with single spaces.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
+
+// normalizeNames returns the distinct normalized names in order, or
+// ErrCheckViolation if any of them is empty or too long.
+func normalizeNames(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, name := range names {
+		n := normalizeName(name)
+		if n == "" || len(n) > maxNameLength {
+			return nil, ErrCheckViolation
+		}
+		if !seen[n] {
+			seen[n] = true
+			normalized = append(normalized, n)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// GetTags returns every tag in use, most used first.
+func (s *SQLStore) GetTags() ([]Tag, error) {
+	rows, err := s.DB.Query(
+		"SELECT t.name, COUNT(*) FROM tags t JOIN recipe_tags rt ON rt.tag_id = t.tag_id " +
+			"JOIN recipes r ON r.id = rt.recipe_id AND r.deleted_at IS NULL " +
+			"GROUP BY t.name ORDER BY COUNT(*) DESC, t.name")
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	tags := []Tag{}
+	for rows.Next() {
+		var t Tag
+		if err := rows.Scan(&t.Name, &t.Recipes); err != nil {
+			return nil, err
+		}
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// GetRecipeTags returns the tags of a specific recipe, in name order.
+func (s *SQLStore) GetRecipeTags(recipeID int) ([]string, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+	return queryNames(s.DB,
+		"SELECT t.name FROM tags t JOIN recipe_tags rt ON rt.tag_id = t.tag_id WHERE rt.recipe_id=$1 ORDER BY t.name",
+		recipeID)
+}
+
+// queryNames returns the names selected by a query.
+func queryNames(q queryer, query string, args ...interface{}) ([]string, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// SetRecipeTags replaces the tags of a specific recipe, creating any new tags.
+func (s *SQLStore) SetRecipeTags(recipeID int, tags []string) ([]string, error) {
+	tags, err := normalizeNames(tags)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL)", recipeID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+	if _, err := tx.Exec("DELETE FROM recipe_tags WHERE recipe_id=$1", recipeID); err != nil {
+		return nil, err
+	}
+	for _, tag := range tags {
+		if _, err := tx.Exec("INSERT INTO tags(name) VALUES($1) ON CONFLICT (name) DO NOTHING", tag); err != nil {
+			return nil, s.mapError(err)
+		}
+		if _, err := tx.Exec("INSERT INTO recipe_tags(recipe_id, tag_id) SELECT $1, tag_id FROM tags WHERE name=$2",
+			recipeID, tag); err != nil {
+			return nil, err
+		}
+	}
+	if err := touchRecipe(tx, recipeID); err != nil {
+		return nil, err
+	}
+	return tags, tx.Commit()
+}
+
+// hasTag reports whether a recipe carries the specified (normalized) tag
+// (the caller holds the lock).
+func (s *MemoryStore) hasTag(recipeID int, tag string) bool {
+	for _, t := range s.tags[recipeID] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTags returns every tag in use, most used first.
+func (s *MemoryStore) GetTags() ([]Tag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := map[string]int{}
+	for recipeID, tags := range s.tags {
+		if _, ok := s.recipes[recipeID]; !ok {
+			continue // in the trash
+		}
+		for _, t := range tags {
+			counts[t]++
+		}
+	}
+	tags := []Tag{}
+	for name, n := range counts {
+		tags = append(tags, Tag{Name: name, Recipes: n})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Recipes != tags[j].Recipes {
+			return tags[i].Recipes > tags[j].Recipes
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+// GetRecipeTags returns the tags of a specific recipe, in name order.
+func (s *MemoryStore) GetRecipeTags(recipeID int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	return append([]string{}, s.tags[recipeID]...), nil
+}
+
+// SetRecipeTags replaces the tags of a specific recipe, creating any new tags.
+func (s *MemoryStore) SetRecipeTags(recipeID int, tags []string) ([]string, error) {
+	tags, err := normalizeNames(tags)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	s.tags[recipeID] = tags
+	s.touch(recipeID)
+	return append([]string{}, tags...), nil
+}
@@ -0,0 +1,279 @@
+package recipes
+
+import "sort"
+
+// The curated taxonomies which recipes may be classified under.
+const (
+	Cuisine = "cuisine"
+	Course  = "course"
+)
+
+// Taxonomies lists every taxonomy.
+var Taxonomies = []string{Cuisine, Course}
+
+// DefaultTerms are the terms each taxonomy starts out with.
+var DefaultTerms = map[string][]string{
+	Cuisine: {"american", "british", "chinese", "french", "greek", "indian", "italian", "japanese",
+		"mexican", "middle eastern", "spanish", "thai"},
+	Course: {"breakfast", "starter", "main", "side", "dessert", "snack", "drink"},
+}
+
+// isTaxonomy reports whether the taxonomy is one of Taxonomies.
+func isTaxonomy(taxonomy string) bool {
+	for _, t := range Taxonomies {
+		if t == taxonomy {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTerm normalizes the term name and enforces the taxonomy_terms table constraints.
+func checkTerm(t *Term) error {
+	t.Name = normalizeName(t.Name)
+	if !isTaxonomy(t.Taxonomy) || t.Name == "" || len(t.Name) > maxNameLength {
+		return ErrCheckViolation
+	}
+	return nil
+}
+
+// GetTerms returns the terms of a taxonomy, in name order.
+func (s *SQLStore) GetTerms(taxonomy string) ([]Term, error) {
+	rows, err := s.DB.Query("SELECT term_id, taxonomy, name FROM taxonomy_terms WHERE taxonomy=$1 ORDER BY name",
+		taxonomy)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	terms := []Term{}
+	for rows.Next() {
+		var t Term
+		if err := rows.Scan(&t.ID, &t.Taxonomy, &t.Name); err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+
+	return terms, rows.Err()
+}
+
+// AddTerm adds a term to a taxonomy.
+func (s *SQLStore) AddTerm(t *Term) error {
+	if err := checkTerm(t); err != nil {
+		return err
+	}
+	// the only unique constraint is on the term name
+	return s.mapDuplicateError(s.DB.QueryRow("INSERT INTO taxonomy_terms(taxonomy, name) VALUES($1, $2) RETURNING term_id",
+		t.Taxonomy, t.Name).Scan(&t.ID), ErrDuplicateTerm)
+}
+
+// DeleteTerm removes a term from its taxonomy (and from every recipe).
+func (s *SQLStore) DeleteTerm(t *Term) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("UPDATE recipes SET version = version + 1 WHERE id IN "+
+		"(SELECT recipe_id FROM recipe_terms WHERE term_id=$1)", t.ID); err != nil {
+		return err
+	}
+	res, err := tx.Exec("DELETE FROM taxonomy_terms WHERE term_id=$1 AND taxonomy=$2", t.ID, t.Taxonomy)
+	if err != nil {
+		return err
+	}
+	if err := checkRowsAffected(res, ErrTermNotFound); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRecipeTerms returns the terms of a taxonomy that a specific recipe is classified under.
+func (s *SQLStore) GetRecipeTerms(recipeID int, taxonomy string) ([]string, error) {
+	exists, err := s.recipeExists(recipeID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+	return queryNames(s.DB,
+		"SELECT tt.name FROM taxonomy_terms tt JOIN recipe_terms rt ON rt.term_id = tt.term_id "+
+			"WHERE rt.recipe_id=$1 AND tt.taxonomy=$2 ORDER BY tt.name",
+		recipeID, taxonomy)
+}
+
+// SetRecipeTerms replaces the terms of a taxonomy that a specific recipe is
+// classified under. Every term must already exist.
+func (s *SQLStore) SetRecipeTerms(recipeID int, taxonomy string, terms []string) ([]string, error) {
+	terms, err := normalizeNames(terms)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM recipes WHERE id=$1 AND deleted_at IS NULL)", recipeID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrRecipeNotFound
+	}
+	if _, err := tx.Exec(
+		"DELETE FROM recipe_terms WHERE recipe_id=$1 AND term_id IN (SELECT term_id FROM taxonomy_terms WHERE taxonomy=$2)",
+		recipeID, taxonomy); err != nil {
+		return nil, err
+	}
+	for _, term := range terms {
+		res, err := tx.Exec(
+			"INSERT INTO recipe_terms(recipe_id, term_id) SELECT $1, term_id FROM taxonomy_terms WHERE taxonomy=$2 AND name=$3",
+			recipeID, taxonomy, term)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkRowsAffected(res, ErrTermNotFound); err != nil {
+			return nil, err
+		}
+	}
+	if err := touchRecipe(tx, recipeID); err != nil {
+		return nil, err
+	}
+	return terms, tx.Commit()
+}
+
+// findTerm returns the ID of the named term, or 0 if it does not exist
+// (the caller holds the lock).
+func (s *MemoryStore) findTerm(taxonomy, name string) int {
+	for id, t := range s.terms {
+		if t.Taxonomy == taxonomy && t.Name == name {
+			return id
+		}
+	}
+	return 0
+}
+
+// hasTerm reports whether a recipe is classified under the specified
+// (normalized) term (the caller holds the lock).
+func (s *MemoryStore) hasTerm(recipeID int, taxonomy, name string) bool {
+	id := s.findTerm(taxonomy, name)
+	for _, termID := range s.recipeTerms[recipeID] {
+		if termID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// addTerm adds a term to a taxonomy (the caller holds the lock).
+func (s *MemoryStore) addTerm(t *Term) error {
+	if err := checkTerm(t); err != nil {
+		return err
+	}
+	if s.findTerm(t.Taxonomy, t.Name) != 0 {
+		return ErrDuplicateTerm
+	}
+	t.ID = s.nextTermID
+	s.nextTermID++
+	s.terms[t.ID] = *t
+	return nil
+}
+
+// GetTerms returns the terms of a taxonomy, in name order.
+func (s *MemoryStore) GetTerms(taxonomy string) ([]Term, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	terms := []Term{}
+	for _, t := range s.terms {
+		if t.Taxonomy == taxonomy {
+			terms = append(terms, t)
+		}
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		return terms[i].Name < terms[j].Name
+	})
+	return terms, nil
+}
+
+// AddTerm adds a term to a taxonomy.
+func (s *MemoryStore) AddTerm(t *Term) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addTerm(t)
+}
+
+// DeleteTerm removes a term from its taxonomy (and from every recipe).
+func (s *MemoryStore) DeleteTerm(t *Term) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.terms[t.ID]
+	if !ok || existing.Taxonomy != t.Taxonomy {
+		return ErrTermNotFound
+	}
+	delete(s.terms, t.ID)
+	for recipeID, termIDs := range s.recipeTerms {
+		kept := []int{}
+		for _, id := range termIDs {
+			if id != t.ID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) != len(termIDs) {
+			s.touch(recipeID)
+		}
+		s.recipeTerms[recipeID] = kept
+	}
+	return nil
+}
+
+// GetRecipeTerms returns the terms of a taxonomy that a specific recipe is classified under.
+func (s *MemoryStore) GetRecipeTerms(recipeID int, taxonomy string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	names := []string{}
+	for _, id := range s.recipeTerms[recipeID] {
+		if t := s.terms[id]; t.Taxonomy == taxonomy {
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// SetRecipeTerms replaces the terms of a taxonomy that a specific recipe is
+// classified under. Every term must already exist.
+func (s *MemoryStore) SetRecipeTerms(recipeID int, taxonomy string, terms []string) ([]string, error) {
+	terms, err := normalizeNames(terms)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.recipes[recipeID]; !ok {
+		return nil, ErrRecipeNotFound
+	}
+	termIDs := []int{}
+	for _, id := range s.recipeTerms[recipeID] {
+		if s.terms[id].Taxonomy != taxonomy {
+			termIDs = append(termIDs, id)
+		}
+	}
+	for _, name := range terms {
+		id := s.findTerm(taxonomy, name)
+		if id == 0 {
+			return nil, ErrTermNotFound
+		}
+		termIDs = append(termIDs, id)
+	}
+	s.recipeTerms[recipeID] = termIDs
+	s.touch(recipeID)
+	return terms, nil
+}
@@ -0,0 +1,172 @@
+package recipes
+
+import (
+	"database/sql"
+	"sort"
+	"time"
+)
+
+// notInTrash is the condition on the rows belonging to recipes (by their
+// recipe_id) which excludes those of recipes in the trash.
+const notInTrash = "recipe_id NOT IN (SELECT id FROM recipes WHERE deleted_at IS NOT NULL)"
+
+// GetDeletedRecipes returns (a page of) the recipes in the trash, most
+// recently deleted first.
+func (s *SQLStore) GetDeletedRecipes(start, count int) ([]Recipe, error) {
+	rows, err := s.DB.Query(
+		"SELECT id, name, preptime, difficulty, vegetarian, servings, created_at, version, owner, deleted_at FROM recipes "+
+			"WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC LIMIT $1 OFFSET $2",
+		count, start)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+	recipes := []Recipe{}
+	for rows.Next() {
+		var r Recipe
+		if err := rows.Scan(&r.ID, &r.Name, &r.PrepTime, &r.Difficulty, &r.Vegetarian, &r.Servings, &r.CreatedAt,
+			&r.Version, &r.Owner, &r.DeletedAt); err != nil {
+			return nil, err
+		}
+		recipes = append(recipes, r)
+	}
+
+	return recipes, rows.Err()
+}
+
+// RestoreRecipe takes a specific recipe out of the trash, populating it. It
+// is restored as a new version (so preconditions on the version deleted
+// fail), recording a revision which changes nothing.
+func (s *SQLStore) RestoreRecipe(r *Recipe) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = tx.QueryRow(
+		"UPDATE recipes SET deleted_at = NULL, version = version + 1 WHERE id=$1 AND deleted_at IS NOT NULL "+
+			"RETURNING name, preptime, difficulty, vegetarian, servings, created_at, version, owner",
+		r.ID).Scan(&r.Name, &r.PrepTime, &r.Difficulty, &r.Vegetarian, &r.Servings, &r.CreatedAt, &r.Version, &r.Owner)
+	if err == sql.ErrNoRows {
+		return ErrRecipeNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := addRevision(tx, r, map[string]Change{}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// PurgeRecipe permanently deletes a specific recipe in the trash (and
+// everything belonging to it).
+func (s *SQLStore) PurgeRecipe(r *Recipe) error {
+	res, err := s.DB.Exec("DELETE FROM recipes WHERE id=$1 AND deleted_at IS NOT NULL", r.ID)
+	if err != nil {
+		return err
+	}
+	return checkRowsAffected(res, ErrRecipeNotFound)
+}
+
+// PurgeRecipes permanently deletes the recipes which were moved to the trash
+// before the time given (and everything belonging to them), returning how
+// many were purged.
+func (s *SQLStore) PurgeRecipes(deletedBefore time.Time) (int, error) {
+	res, err := s.DB.Exec("DELETE FROM recipes WHERE deleted_at < $1", deletedBefore.UTC())
+	if err != nil {
+		return 0, err
+	}
+	purged, err := res.RowsAffected()
+	return int(purged), err
+}
+
+// GetDeletedRecipes returns (a page of) the recipes in the trash, most
+// recently deleted first.
+func (s *MemoryStore) GetDeletedRecipes(start, count int) ([]Recipe, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	recipes := []Recipe{}
+	for _, r := range s.trash {
+		recipes = append(recipes, r)
+	}
+	sort.Slice(recipes, func(i, j int) bool {
+		if !recipes[i].DeletedAt.Equal(*recipes[j].DeletedAt) {
+			return recipes[i].DeletedAt.After(*recipes[j].DeletedAt)
+		}
+		return recipes[i].ID > recipes[j].ID
+	})
+	if start >= len(recipes) {
+		return []Recipe{}, nil
+	}
+	recipes = recipes[start:]
+	if len(recipes) > count {
+		recipes = recipes[:count]
+	}
+	return recipes, nil
+}
+
+// RestoreRecipe takes a specific recipe out of the trash, populating it. It
+// is restored as a new version (so preconditions on the version deleted
+// fail), recording a revision which changes nothing.
+func (s *MemoryStore) RestoreRecipe(r *Recipe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted, ok := s.trash[r.ID]
+	if !ok {
+		return ErrRecipeNotFound
+	}
+	deleted.DeletedAt = nil
+	deleted.Version++
+	deleted.ModifiedBy = r.ModifiedBy
+	delete(s.trash, r.ID)
+	s.recipes[r.ID] = deleted
+	s.addRevision(&deleted, map[string]Change{})
+	*r = deleted
+	return nil
+}
+
+// PurgeRecipes permanently deletes the recipes which were moved to the trash
+// before the time given (and everything belonging to them), returning how
+// many were purged.
+func (s *MemoryStore) PurgeRecipes(deletedBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for id, r := range s.trash {
+		if r.DeletedAt.Before(deletedBefore) {
+			s.purge(id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PurgeRecipe permanently deletes a specific recipe in the trash (and
+// everything belonging to it).
+func (s *MemoryStore) PurgeRecipe(r *Recipe) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.trash[r.ID]; !ok {
+		return ErrRecipeNotFound
+	}
+	s.purge(r.ID)
+	return nil
+}
+
+// purge deletes a recipe in the trash and everything belonging to it (the
+// caller holds the lock).
+func (s *MemoryStore) purge(recipeID int) {
+	delete(s.trash, recipeID)
+	delete(s.ratings, recipeID)
+	for _, rv := range s.reviews[recipeID] {
+		delete(s.reviewFlags, rv.ID)
+	}
+	delete(s.reviews, recipeID)
+	delete(s.ingredients, recipeID)
+	delete(s.steps, recipeID)
+	delete(s.tags, recipeID)
+	delete(s.recipeTerms, recipeID)
+	delete(s.revisions, recipeID)
+}
@@ -0,0 +1,230 @@
+package recipes
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The supported systems of measurement.
+const (
+	Metric   = "metric"
+	Imperial = "imperial" // in practice US customary units, as used in most recipes
+)
+
+// The kinds of quantity which can be converted.
+const (
+	volume      = "volume"
+	weight      = "weight"
+	temperature = "temperature"
+)
+
+// A unitConversion describes a unit in terms of its base unit (millilitres
+// for volumes and grams for weights).
+type unitConversion struct {
+	kind   string
+	metric bool
+	toBase float64
+}
+
+// conversions is the table of known units, keyed by canonical name.
+var conversions = map[string]unitConversion{
+	"tsp":    {volume, false, 4.92892},
+	"tbsp":   {volume, false, 14.7868},
+	"fl oz":  {volume, false, 29.5735},
+	"cup":    {volume, false, 236.588},
+	"pint":   {volume, false, 473.176},
+	"quart":  {volume, false, 946.353},
+	"gallon": {volume, false, 3785.41},
+	"ml":     {volume, true, 1},
+	"cl":     {volume, true, 10},
+	"dl":     {volume, true, 100},
+	"l":      {volume, true, 1000},
+	"oz":     {weight, false, 28.3495},
+	"lb":     {weight, false, 453.592},
+	"mg":     {weight, true, 0.001},
+	"g":      {weight, true, 1},
+	"kg":     {weight, true, 1000},
+	"°F":     {temperature, false, 0},
+	"°C":     {temperature, true, 0},
+}
+
+// densityHints gives the approximate density (grams per millilitre) of
+// ingredients which are measured by volume in US recipes but weighed in
+// metric ones. The longest matching name wins ("brown sugar" over "sugar").
+var densityHints = map[string]float64{
+	"flour":          0.53,
+	"bread flour":    0.55,
+	"sugar":          0.85,
+	"brown sugar":    0.93,
+	"caster sugar":   0.85,
+	"icing sugar":    0.51,
+	"powdered sugar": 0.51,
+	"butter":         0.96,
+	"rice":           0.78,
+	"oats":           0.38,
+	"cocoa":          0.36,
+	"cornstarch":     0.54,
+	"cornflour":      0.54,
+	"salt":           1.22,
+	"honey":          1.42,
+	"grated cheese":  0.42,
+	"breadcrumbs":    0.46,
+}
+
+// unitAliases maps the common spellings of each unit onto its canonical name.
+var unitAliases = map[string]string{
+	"teaspoon": "tsp", "teaspoons": "tsp", "tsp": "tsp", "tsps": "tsp",
+	"tablespoon": "tbsp", "tablespoons": "tbsp", "tbsp": "tbsp", "tbsps": "tbsp", "tbs": "tbsp",
+	"cup": "cup", "cups": "cup",
+	"fluid ounce": "fl oz", "fluid ounces": "fl oz", "fl oz": "fl oz", "fl. oz": "fl oz",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"quart": "quart", "quarts": "quart", "qt": "quart",
+	"gallon": "gallon", "gallons": "gallon", "gal": "gallon",
+	"ounce": "oz", "ounces": "oz", "oz": "oz",
+	"pound": "lb", "pounds": "lb", "lb": "lb", "lbs": "lb",
+	"milliliter": "ml", "milliliters": "ml", "millilitre": "ml", "millilitres": "ml", "ml": "ml",
+	"centiliter": "cl", "centiliters": "cl", "centilitre": "cl", "centilitres": "cl", "cl": "cl",
+	"deciliter": "dl", "deciliters": "dl", "decilitre": "dl", "decilitres": "dl", "dl": "dl",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l", "l": "l",
+	"milligram": "mg", "milligrams": "mg", "mg": "mg",
+	"gram": "g", "grams": "g", "g": "g",
+	"kilogram": "kg", "kilograms": "kg", "kg": "kg",
+	"°f": "°F", "fahrenheit": "°F", "degrees f": "°F", "degrees fahrenheit": "°F",
+	"°c": "°C", "celsius": "°C", "degrees c": "°C", "degrees celsius": "°C",
+}
+
+// canonicalUnit returns the canonical name of a unit, and whether it is known.
+func canonicalUnit(unit string) (string, bool) {
+	key := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(unit)), ".")
+	canonical, ok := unitAliases[key]
+	return canonical, ok
+}
+
+// isMetric reports whether a (canonical) unit is a metric unit.
+func isMetric(canonical string) bool {
+	return conversions[canonical].metric
+}
+
+// density returns the density hint for an ingredient, if there is one.
+func density(item string) (float64, bool) {
+	item = strings.ToLower(item)
+	match := ""
+	for name := range densityHints {
+		if len(name) > len(match) && strings.Contains(item, name) {
+			match = name
+		}
+	}
+	d, ok := densityHints[match]
+	return d, ok
+}
+
+// IsValidSystem reports whether system is a supported system of measurement.
+func IsValidSystem(system string) bool {
+	return system == Metric || system == Imperial
+}
+
+// ConvertIngredients returns copies of the ingredients with their quantities
+// converted into the specified system of measurement (and sensibly rounded).
+// Ingredients in unknown units (or none, such as eggs) are left unchanged.
+func ConvertIngredients(ingredients []Ingredient, system string) []Ingredient {
+	converted := make([]Ingredient, 0, len(ingredients))
+	for _, i := range ingredients {
+		converted = append(converted, convertIngredient(i, system))
+	}
+	return converted
+}
+
+// convertIngredient converts a single ingredient into the specified system.
+func convertIngredient(i Ingredient, system string) Ingredient {
+	canonical, known := canonicalUnit(i.Unit)
+	if !known || isMetric(canonical) == (system == Metric) {
+		return i
+	}
+	from := conversions[canonical]
+	if from.kind == temperature {
+		i.Quantity, i.Unit = convertTemperature(i.Quantity, system)
+		return i
+	}
+
+	base := i.Quantity * from.toBase
+	kind := from.kind
+	if d, ok := density(i.Item); ok {
+		// Metric recipes weigh dry ingredients, US recipes measure them in cups
+		switch {
+		case system == Metric && kind == volume:
+			base, kind = base*d, weight
+		case system == Imperial && kind == weight:
+			base, kind = base/d, volume
+		}
+	}
+	i.Unit = bestUnit(base, kind, system)
+	i.Quantity = RoundQuantity(base/conversions[i.Unit].toBase, i.Unit)
+	return i
+}
+
+// bestUnit chooses the most natural unit for a quantity (given in the base unit).
+func bestUnit(base float64, kind string, system string) string {
+	switch {
+	case kind == volume && system == Metric:
+		if base >= 1000 {
+			return "l"
+		}
+		return "ml"
+	case kind == weight && system == Metric:
+		if base >= 1000 {
+			return "kg"
+		}
+		return "g"
+	case kind == volume:
+		switch {
+		case base < conversions["tbsp"].toBase:
+			return "tsp"
+		case base < conversions["cup"].toBase/4:
+			return "tbsp"
+		}
+		return "cup"
+	}
+	if base < conversions["lb"].toBase {
+		return "oz"
+	}
+	return "lb"
+}
+
+// convertTemperature converts a temperature into the specified system,
+// rounded to the nearest 5 degrees (oven dials are not very precise).
+func convertTemperature(degrees float64, system string) (float64, string) {
+	if system == Metric {
+		return math.Round((degrees-32)*5/9/5) * 5, "°C"
+	}
+	return math.Round((degrees*9/5+32)/5) * 5, "°F"
+}
+
+// temperaturePattern matches temperatures such as "350°F", "180 °C",
+// "200C" and "425 degrees Fahrenheit" in free text.
+var temperaturePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)(\s*°\s*|\s*degrees\s+|)(fahrenheit|celsius|f|c)\b`)
+
+// ConvertSteps returns copies of the steps with any temperatures mentioned
+// in their text converted into the specified system of measurement.
+func ConvertSteps(steps []Step, system string) []Step {
+	converted := make([]Step, 0, len(steps))
+	for _, st := range steps {
+		st.Text = temperaturePattern.ReplaceAllStringFunc(st.Text, func(match string) string {
+			parts := temperaturePattern.FindStringSubmatch(match)
+			celsius := strings.ToLower(parts[3])[0] == 'c'
+			if celsius == (system == Metric) {
+				return match
+			}
+			degrees, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return match
+			}
+			value, unit := convertTemperature(degrees, system)
+			return fmt.Sprintf("%g%s", value, unit)
+		})
+		converted = append(converted, st)
+	}
+	return converted
+}
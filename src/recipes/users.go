@@ -0,0 +1,106 @@
+package recipes
+
+import "database/sql"
+
+// checkUser enforces the users table constraints. Users are contributors
+// unless they are given another role.
+func checkUser(u *User) error {
+	if u.Role == "" {
+		u.Role = Contributor
+	}
+	if u.Username == "" || !validRole(u.Role) {
+		return ErrCheckViolation
+	}
+	return nil
+}
+
+// validRole reports whether a role is one of the Roles.
+func validRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateUser is used to create a single user account.
+func (s *SQLStore) CreateUser(u *User) error {
+	if err := checkUser(u); err != nil {
+		return err
+	}
+	u.CreatedAt = now()
+	// the only unique constraint is on the username
+	return s.mapDuplicateError(s.DB.QueryRow(
+		"INSERT INTO users(username, role, password_hash, created_at) VALUES($1, $2, $3, $4) RETURNING user_id",
+		u.Username, u.Role, u.PasswordHash, u.CreatedAt).Scan(&u.ID), ErrDuplicateUser)
+}
+
+// GetUser populates the specified user (by username).
+func (s *SQLStore) GetUser(u *User) error {
+	err := s.DB.QueryRow("SELECT user_id, role, password_hash, created_at FROM users WHERE username=$1",
+		u.Username).Scan(&u.ID, &u.Role, &u.PasswordHash, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// SetUserRole gives the specified user (by username) a role, populating them.
+func (s *SQLStore) SetUserRole(u *User) error {
+	if !validRole(u.Role) {
+		return ErrCheckViolation
+	}
+	err := s.DB.QueryRow("UPDATE users SET role=$1 WHERE username=$2 RETURNING user_id, created_at",
+		u.Role, u.Username).Scan(&u.ID, &u.CreatedAt)
+	if err == sql.ErrNoRows {
+		return ErrUserNotFound
+	}
+	return s.mapError(err)
+}
+
+// CreateUser is used to create a single user account.
+func (s *MemoryStore) CreateUser(u *User) error {
+	if err := checkUser(u); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[u.Username]; ok {
+		return ErrDuplicateUser
+	}
+	u.ID = s.nextUserID
+	s.nextUserID++
+	u.CreatedAt = now()
+	s.users[u.Username] = *u
+	return nil
+}
+
+// GetUser populates the specified user (by username).
+func (s *MemoryStore) GetUser(u *User) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	existing, ok := s.users[u.Username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	*u = existing
+	return nil
+}
+
+// SetUserRole gives the specified user (by username) a role, populating them.
+func (s *MemoryStore) SetUserRole(u *User) error {
+	if !validRole(u.Role) {
+		return ErrCheckViolation
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	existing, ok := s.users[u.Username]
+	if !ok {
+		return ErrUserNotFound
+	}
+	existing.Role = u.Role
+	s.users[u.Username] = existing
+	*u = existing
+	return nil
+}
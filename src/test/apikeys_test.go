@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	clearTables()
+	registerUser(t, "ingest", "")
+
+	key := createAPIKey(t, `{"name":"ingestion","username":"ingest","scope":"write"}`)
+	assert.Equalf(t, key["key_id"], 1.0, "Expected key ID to be '1'. Got '%v'", key["key_id"])
+	secret, _ := key["key"].(string)
+	assert.Truef(t, strings.HasPrefix(secret, key["prefix"].(string)), "Expected the key to start with '%v'. Got '%v'", key["prefix"], secret)
+	assert.Nilf(t, key["last_used_at"], "Expected no 'last_used_at'. Got '%v'", key["last_used_at"])
+
+	// the key itself is never listed
+	keys := getAPIKeys(t)
+	assert.Equalf(t, len(keys), 1, "Expected 1 API key. Got '%v'", len(keys))
+	assert.NotContainsf(t, keys[0], "key", "Expected no 'key'. Got '%v'", keys[0]["key"])
+
+	for payload, code := range map[string]int{
+		`{"name":"","scope":"write"}`:                                             http.StatusUnprocessableEntity,
+		`{"name":"ingestion","scope":"admin"}`:                                    http.StatusUnprocessableEntity,
+		`{"name":"ingestion","scope":"read","username":"nobody"}`:                 http.StatusUnprocessableEntity,
+		`{"name":"ingestion","scope":"read","expires_at":"2000-01-01T00:00:00Z"}`: http.StatusUnprocessableEntity,
+	} {
+		response := conditionalRequest("POST", "/v1/apikeys", "", "", payload)
+		assert.Equalf(t, response.Code, code, "Expected response code %d for '%s'. Got %d", code, payload, response.Code)
+	}
+
+	// only admins manage API keys
+	response := bearerRequest("GET", "/v1/apikeys", secret, "")
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+}
+
+func TestUseAPIKeys(t *testing.T) {
+	clearTables()
+	registerUser(t, "ingest", "")
+	write := createAPIKey(t, `{"name":"ingestion","username":"ingest","scope":"write"}`)["key"].(string)
+	read := createAPIKey(t, `{"name":"reporting","scope":"read"}`)["key"].(string)
+
+	// write keys act as their user, by either header
+	payload := `{"name":"Pad Thai","preptime":30,"difficulty":2}`
+	response := apiKeyRequest("POST", "/v1/recipes", write, payload)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["owner"], "ingest", "Expected owner 'ingest'. Got '%v'", m["owner"])
+	response = bearerRequest("PATCH", "/v1/recipes/1", write, `{"servings":2}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// while read keys may only read (even when issued for an admin)
+	response = apiKeyRequest("PATCH", "/v1/recipes/1", read, `{"servings":4}`)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	keys := getAPIKeys(t)
+	assert.NotNilf(t, keys[0]["last_used_at"], "Expected a 'last_used_at'. Got '%v'", keys[0]["last_used_at"])
+	assert.NotNilf(t, keys[1]["last_used_at"], "Expected a 'last_used_at'. Got '%v'", keys[1]["last_used_at"])
+
+	// revoked keys, and any others, are not accepted
+	response = conditionalRequest("DELETE", "/v1/apikeys/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = conditionalRequest("DELETE", "/v1/apikeys/1", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	for _, key := range []string{write, "rrk_unknown"} {
+		response = apiKeyRequest("PATCH", "/v1/recipes/1", key, `{"servings":4}`)
+		checkResponseCode(t, http.StatusUnauthorized, response.Code)
+	}
+}
+
+func TestExpiredAPIKey(t *testing.T) {
+	clearTables()
+	expiresAt := time.Now().Add(time.Second).UTC().Format(time.RFC3339Nano)
+	key := createAPIKey(t, `{"name":"short-lived","scope":"write","expires_at":"`+expiresAt+`"}`)
+	assert.NotNilf(t, key["expires_at"], "Expected an 'expires_at'. Got '%v'", key["expires_at"])
+
+	payload := `{"name":"Pad Thai","preptime":30,"difficulty":2}`
+	response := apiKeyRequest("POST", "/v1/recipes", key["key"].(string), payload)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	time.Sleep(time.Second)
+	response = apiKeyRequest("DELETE", "/v1/recipes/1", key["key"].(string), "")
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func apiKeyRequest(method, path, key, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(payload))
+	req.Header.Set("X-API-Key", key)
+	return executeRequest(req)
+}
+
+func createAPIKey(t *testing.T, payload string) map[string]interface{} {
+	response := conditionalRequest("POST", "/v1/apikeys", "", "", payload)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var key map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &key)
+	return key
+}
+
+func getAPIKeys(t *testing.T) []map[string]interface{} {
+	response := conditionalRequest("GET", "/v1/apikeys", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var keys []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &keys)
+	return keys
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterUser(t *testing.T) {
+	clearTables()
+
+	response := authRequest("/v1/auth/register", `{"username":"julia","password":"mastering"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["username"], "julia", "Expected username 'julia'. Got '%v'", m["username"])
+	assert.Equalf(t, m["user_id"], 1.0, "Expected user ID to be '1'. Got '%v'", m["user_id"])
+	assert.NotContainsf(t, response.Body.String(), "mastering", "Expected no password. Got '%v'", response.Body.String())
+	assert.NotContainsf(t, m, "password_hash", "Expected no password hash. Got '%v'", m["password_hash"])
+
+	for payload, code := range map[string]int{
+		`{"username":"julia","password":"different"}`:             http.StatusConflict,
+		`{"username":"jacques","password":"short"}`:               http.StatusUnprocessableEntity,
+		`{"username":"","password":"mastering"}`:                  http.StatusUnprocessableEntity,
+		`{"username":"jacques","password":"mastering","admin":1}`: http.StatusUnprocessableEntity,
+		`{"username":"jacques"`:                                   http.StatusBadRequest,
+	} {
+		response = authRequest("/v1/auth/register", payload)
+		assert.Equalf(t, response.Code, code, "Expected response code %d for '%s'. Got %d", code, payload, response.Code)
+	}
+
+	// nor may anyone register as the configured user
+	response = authRequest("/v1/auth/register", `{"username":"`+authUser+`","password":"attacker-pass"}`)
+	checkProblem(t, response, http.StatusConflict, "/problems/duplicate")
+	response = authRequest("/v1/auth/login", `{"username":"`+authUser+`","password":"attacker-pass"}`)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestLoginAndUseTokens(t *testing.T) {
+	clearTables()
+	authRequest("/v1/auth/register", `{"username":"julia","password":"mastering"}`)
+
+	for _, payload := range []string{`{"username":"julia","password":"wrong"}`, `{"username":"nobody","password":"mastering"}`} {
+		response := authRequest("/v1/auth/login", payload)
+		checkResponseCode(t, http.StatusUnauthorized, response.Code)
+	}
+
+	tokens := login(t, `{"username":"julia","password":"mastering"}`)
+	assert.Equalf(t, tokens["token_type"], "Bearer", "Expected token type 'Bearer'. Got '%v'", tokens["token_type"])
+	assert.Equalf(t, tokens["expires_in"], 900.0, "Expected expires_in '900'. Got '%v'", tokens["expires_in"])
+
+	// the access token authenticates the user (who is the author of their changes)
+	payload := `{"name":"Boeuf Bourguignon","preptime":180,"difficulty":3}`
+	response := bearerRequest("POST", "/v1/recipes", tokens["access_token"].(string), payload)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	revisions := getRevisions(t, "/v1/recipes/1/revisions")
+	author := revisions[0].(map[string]interface{})["author"]
+	assert.Equalf(t, author, "julia", "Expected author 'julia'. Got '%v'", author)
+
+	// the refresh token is not an access token, nor can it be tampered with
+	refresh := tokens["refresh_token"].(string)
+	access := tokens["access_token"].(string)
+	for _, token := range []string{refresh, access[:len(access)-2], "not.a.token", ""} {
+		response = bearerRequest("DELETE", "/v1/recipes/1", token, "")
+		checkResponseCode(t, http.StatusUnauthorized, response.Code)
+		assert.NotEmptyf(t, response.Header().Get("WWW-Authenticate"), "Expected a WWW-Authenticate header")
+	}
+
+	// but it is exchanged for new tokens
+	response = authRequest("/v1/auth/refresh", `{"refresh_token":"`+refresh+`"}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var refreshed map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &refreshed)
+	response = bearerRequest("DELETE", "/v1/recipes/1", refreshed["access_token"].(string), "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	for _, token := range []string{access, "not.a.token"} {
+		response = authRequest("/v1/auth/refresh", `{"refresh_token":"`+token+`"}`)
+		checkResponseCode(t, http.StatusUnauthorized, response.Code)
+	}
+}
+
+func TestRejectedTokens(t *testing.T) {
+	clearTables()
+	authRequest("/v1/auth/register", `{"username":"julia","password":"mastering"}`)
+	tokens := login(t, `{"username":"julia","password":"mastering"}`)
+	response := bearerRequest("POST", "/v1/recipes", tokens["access_token"].(string), `{"name":"Cassoulet","preptime":240,"difficulty":3}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	now := time.Now().Unix()
+	sign := func(method jwt.SigningMethod, secret []byte, claims jwt.MapClaims) string {
+		token, _ := jwt.NewWithClaims(method, claims).SignedString(secret)
+		return token
+	}
+	valid := jwt.MapClaims{"sub": "julia", "use": "access", "iat": now, "exp": now + 60}
+	parts := strings.Split(tokens["access_token"].(string), ".")
+	encode := base64.RawURLEncoding.EncodeToString
+	for reason, token := range map[string]string{
+		"with alg none":              encode([]byte(`{"alg":"none","typ":"JWT"}`)) + "." + parts[1] + ".",
+		"with alg HS512":             sign(jwt.SigningMethodHS512, app.TokenSecret, valid),
+		"with a bad signature":       parts[0] + "." + parts[1] + "." + encode([]byte("not the signature")),
+		"signed with another secret": sign(jwt.SigningMethodHS256, []byte("another secret"), valid),
+		"which has expired":          sign(jwt.SigningMethodHS256, app.TokenSecret, jwt.MapClaims{"sub": "julia", "use": "access", "iat": now - 3600, "exp": now - 60}),
+		"which never expires":        sign(jwt.SigningMethodHS256, app.TokenSecret, jwt.MapClaims{"sub": "julia", "use": "access", "iat": now}),
+	} {
+		response = bearerRequest("DELETE", "/v1/recipes/1", token, "")
+		assert.Equalf(t, response.Code, http.StatusUnauthorized, "Expected response code %d for a token %s. Got %d", http.StatusUnauthorized, reason, response.Code)
+	}
+
+	response = bearerRequest("DELETE", "/v1/recipes/1", sign(jwt.SigningMethodHS256, app.TokenSecret, valid), "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func TestBasicAuthForUsers(t *testing.T) {
+	clearTables()
+	authRequest("/v1/auth/register", `{"username":"julia","password":"mastering"}`)
+
+	payload := `{"name":"Coq au Vin","preptime":150,"difficulty":2}`
+	req, _ := http.NewRequest("POST", "/v1/recipes", strings.NewReader(payload))
+	req.SetBasicAuth("julia", "mastering")
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	req, _ = http.NewRequest("DELETE", "/v1/recipes/1", nil)
+	req.SetBasicAuth("julia", "wrong")
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+
+	// the configured user may log in as well
+	tokens := login(t, `{"username":"`+authUser+`","password":"`+authPassword+`"}`)
+	response = bearerRequest("DELETE", "/v1/recipes/1", tokens["access_token"].(string), "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func authRequest(path, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("POST", path, bytes.NewBufferString(payload))
+	return executeRequest(req)
+}
+
+func bearerRequest(method, path, token, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	return executeRequest(req)
+}
+
+func login(t *testing.T, payload string) map[string]interface{} {
+	response := authRequest("/v1/auth/login", payload)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var tokens map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &tokens)
+	return tokens
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"strings"
+	"testing"
+	// local import
+	"recipes"
+)
+
+func TestRecipesAreOwned(t *testing.T) {
+	clearTables()
+	julia := registerUser(t, "julia", "")
+	jacques := registerUser(t, "jacques", "")
+	editor := registerUser(t, "marcella", "editor")
+
+	payload := `{"name":"Boeuf Bourguignon","preptime":180,"difficulty":3}`
+	response := bearerRequest("POST", "/v1/recipes", julia, payload)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	response = conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["owner"], "julia", "Expected owner 'julia'. Got '%v'", m["owner"])
+
+	// contributors may only modify their own recipes
+	payload = `{"name":"Boeuf Bourguignon","preptime":200,"difficulty":3}`
+	ingredient := `{"quantity":1,"unit":"kg","item":"beef"}`
+	for _, token := range []string{julia, jacques, editor} {
+		expected := http.StatusOK
+		if token == jacques {
+			expected = http.StatusForbidden
+		}
+		response = bearerRequest("PUT", "/v1/recipes/1", token, payload)
+		checkResponseCode(t, expected, response.Code)
+		response = bearerRequest("POST", "/v1/recipes/1/ingredients", token, ingredient)
+		if expected == http.StatusOK {
+			expected = http.StatusCreated
+		}
+		checkResponseCode(t, expected, response.Code)
+	}
+	response = bearerRequest("DELETE", "/v1/recipes/1", jacques, "")
+	checkProblem(t, response, http.StatusForbidden, "/problems/forbidden")
+
+	// an edit by someone else does not change the owner
+	response = conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["owner"], "julia", "Expected owner 'julia'. Got '%v'", m["owner"])
+
+	// recipes which are not found are not found, whoever asks
+	response = bearerRequest("DELETE", "/v1/recipes/99", jacques, "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestRolesAreEnforced(t *testing.T) {
+	clearTables()
+	viewer := registerUser(t, "viewer", "viewer")
+	contributor := registerUser(t, "julia", "")
+	editor := registerUser(t, "marcella", "editor")
+	addRecipes(1)
+
+	// existing recipes have no owner, so only editors may modify them
+	for token, code := range map[string]int{viewer: http.StatusForbidden, contributor: http.StatusForbidden, editor: http.StatusOK} {
+		response := bearerRequest("PATCH", "/v1/recipes/1", token, `{"servings":4}`)
+		checkResponseCode(t, code, response.Code)
+	}
+	payload := `{"name":"Pad Thai","preptime":30,"difficulty":2}`
+	response := bearerRequest("POST", "/v1/recipes", viewer, payload)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	// as are the taxonomies
+	response = bearerRequest("POST", "/v1/cuisines", contributor, `{"name":"peruvian"}`)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = bearerRequest("POST", "/v1/cuisines", editor, `{"name":"peruvian"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	// the trash is managed by editors, but only admins may purge it
+	response = bearerRequest("DELETE", "/v1/recipes/1", editor, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = bearerRequest("GET", "/v1/trash", contributor, "")
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = bearerRequest("GET", "/v1/trash", editor, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = bearerRequest("DELETE", "/v1/trash/1", editor, "")
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = conditionalRequest("DELETE", "/v1/trash/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = conditionalRequest("POST", "/v1/recipes/1/restore", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	response = conditionalRequest("DELETE", "/v1/trash/1", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestSetUserRole(t *testing.T) {
+	clearTables()
+	editor := registerUser(t, "marcella", "editor")
+
+	response := bearerRequest("PUT", "/v1/users/marcella/role", editor, `{"role":"admin"}`)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	for payload, code := range map[string]int{
+		`{"role":"chef"}`: http.StatusUnprocessableEntity,
+		`{"role":""}`:     http.StatusUnprocessableEntity,
+		`{"role":"admin"`: http.StatusBadRequest,
+	} {
+		response = conditionalRequest("PUT", "/v1/users/marcella/role", "", "", payload)
+		assert.Equalf(t, response.Code, code, "Expected response code %d for '%s'. Got %d", code, payload, response.Code)
+	}
+	response = conditionalRequest("PUT", "/v1/users/nobody/role", "", "", `{"role":"admin"}`)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	response = conditionalRequest("PUT", "/v1/users/marcella/role", "", "", `{"role":"admin"}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["role"], "admin", "Expected role 'admin'. Got '%v'", m["role"])
+
+	// which applies straight away (even to tokens already issued)
+	response = bearerRequest("PUT", "/v1/users/marcella/role", editor, `{"role":"editor"}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func TestConfiguredUsernameIsNotEnough(t *testing.T) {
+	clearTables()
+	// an account with the configured username (which cannot be registered
+	// now, but may have been before) is just another user
+	hash, _ := bcrypt.GenerateFromPassword([]byte("attacker-pass"), bcrypt.MinCost)
+	u := recipes.User{Username: authUser, PasswordHash: string(hash)}
+	assert.Nilf(t, app.Store.CreateUser(&u), "Expected to be able to create user '%s'", authUser)
+
+	// granting a role is for admins only
+	path, payload := "/v1/users/"+authUser+"/role", `{"role":"admin"}`
+	tokens := login(t, `{"username":"`+authUser+`","password":"attacker-pass"}`)
+	response := bearerRequest("PUT", path, tokens["access_token"].(string), payload)
+	checkProblem(t, response, http.StatusForbidden, "/problems/forbidden")
+	response = authRequest("/v1/auth/refresh", `{"refresh_token":"`+tokens["refresh_token"].(string)+`"}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var refreshed map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &refreshed)
+	response = bearerRequest("PUT", path, refreshed["access_token"].(string), payload)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	req, _ := http.NewRequest("PUT", path, strings.NewReader(payload))
+	req.SetBasicAuth(authUser, "attacker-pass")
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+
+	// while the configured pair (and the tokens issued for it) still is the admin
+	payload = `{"role":"editor"}`
+	response = conditionalRequest("PUT", path, "", "", payload)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	tokens = login(t, `{"username":"`+authUser+`","password":"`+authPassword+`"}`)
+	response = bearerRequest("PUT", path, tokens["access_token"].(string), payload)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+// registerUser registers a user (giving them a role, unless it is ""), and
+// returns their access token.
+func registerUser(t *testing.T, username, role string) string {
+	credentials := `{"username":"` + username + `","password":"password"}`
+	response := authRequest("/v1/auth/register", credentials)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	if role != "" {
+		response = conditionalRequest("PUT", "/v1/users/"+username+"/role", "", "", `{"role":"`+role+`"}`)
+		checkResponseCode(t, http.StatusOK, response.Code)
+	}
+	return login(t, credentials)["access_token"].(string)
+}
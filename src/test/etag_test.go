@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetRecipeETag(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	etag := response.Header().Get("ETag")
+	assert.Equalf(t, etag, `"1"`, `Expected ETag '"1"'. Got '%v'`, etag)
+
+	// an unchanged recipe is not sent again
+	for _, header := range []string{etag, `W/"1"`, `"7", "1"`, "*"} {
+		response = conditionalRequest("GET", "/v1/recipes/1", "If-None-Match", header, "")
+		checkResponseCode(t, http.StatusNotModified, response.Code)
+		assert.Equalf(t, response.Body.Len(), 0, "Expected an empty body. Got '%v'", response.Body.String())
+		assert.Equalf(t, response.Header().Get("ETag"), etag, "Expected ETag '%v'. Got '%v'", etag, response.Header().Get("ETag"))
+	}
+
+	// anything shown along with the recipe changes its version
+	addIngredient(1, `{"quantity":1,"unit":"cup","item":"flour"}`)
+	response = conditionalRequest("GET", "/v1/recipes/1", "If-None-Match", etag, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	assert.Equalf(t, response.Header().Get("ETag"), `"2"`, `Expected ETag '"2"'. Got '%v'`, response.Header().Get("ETag"))
+}
+
+func TestUpdatePutRecipeIfMatch(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	payload := `{"name":"Recipe 0a","preptime":10,"difficulty":1,"vegetarian":true}`
+	response := conditionalRequest("PUT", "/v1/recipes/1", "If-Match", `"1"`, payload)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	assert.Equalf(t, response.Header().Get("ETag"), `"2"`, `Expected ETag '"2"'. Got '%v'`, response.Header().Get("ETag"))
+
+	// a stale (or weak) ETag no longer matches, so the modification is refused
+	for _, header := range []string{`"1"`, `W/"2"`} {
+		response = conditionalRequest("PUT", "/v1/recipes/1", "If-Match", header, `{"name":"Recipe 0b","difficulty":1}`)
+		checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+	}
+	response = conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	assert.Containsf(t, response.Body.String(), "Recipe 0a", "Expected 'Recipe 0a'. Got '%v'", response.Body.String())
+
+	response = conditionalRequest("PUT", "/v1/recipes/1", "If-Match", "*", `{"name":"Recipe 0b","difficulty":1}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = conditionalRequest("PUT", "/v1/recipes/2", "If-Match", "*", `{"name":"Recipe 0b","difficulty":1}`)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestPatchRecipeIfMatch(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := conditionalRequest("PATCH", "/v1/recipes/1", "If-Match", `"2"`, `{"servings":4}`)
+	checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+
+	response = conditionalRequest("PATCH", "/v1/recipes/1", "If-Match", `"1"`, `{"servings":4}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	assert.Equalf(t, response.Header().Get("ETag"), `"2"`, `Expected ETag '"2"'. Got '%v'`, response.Header().Get("ETag"))
+	assert.Containsf(t, response.Body.String(), `"version":2`, "Expected version '2'. Got '%v'", response.Body.String())
+}
+
+func TestDeleteRecipeIfMatch(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := conditionalRequest("DELETE", "/v1/recipes/1", "If-Match", `"2"`, "")
+	checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+	response = conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	response = conditionalRequest("DELETE", "/v1/recipes/1", "If-Match", `"1"`, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func conditionalRequest(method, path, header, value, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, bytes.NewBufferString(payload))
+	if header != "" {
+		req.Header.Set(header, value)
+	}
+	req.SetBasicAuth(authUser, authPassword)
+	return executeRequest(req)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestSearchWithFacets(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+	addRecipes(1) // "Recipe 0": 10 minutes, difficulty 1
+	setRecipeTags(1, `{"tags":["soup","quick"]}`)
+	setRecipeTags(2, `{"tags":["quick"]}`)
+	setRecipeTags(4, `{"tags":["quick"]}`)
+	addRecipeRating(1, 4)
+	addRecipeRating(1, 5)
+	addRecipeRating(2, 2)
+
+	// the facets are counted over every recipe which matches (not just the page)
+	m, _ := getRecipesPage(t, "POST", "/v1/search/recipes?facets=true&max_preptime=30&count=1")
+	page := m["recipes"].([]interface{})
+	assert.Equalf(t, len(page), 1, "Expected 1 recipe. Got '%v'", len(page))
+	assert.Equalf(t, m["total"], 3.0, "Expected a total of '3'. Got '%v'", m["total"])
+	facets := m["facets"].(map[string]interface{})
+
+	for facet, expected := range map[string]map[string]float64{
+		"difficulty": {"1": 2, "2": 1, "3": 0},
+		"vegetarian": {"true": 3, "false": 0},
+		"preptime":   {"under 15": 1, "15-30": 1, "30-60": 1, "60-120": 0, "120+": 0},
+		"tags":       {"quick": 3, "soup": 1},
+		"rating":     {"unrated": 1, "1-2": 0, "2-3": 1, "3-4": 0, "4-5": 1},
+	} {
+		counts := map[string]float64{}
+		for _, c := range facets[facet].([]interface{}) {
+			counts[c.(map[string]interface{})["value"].(string)] = c.(map[string]interface{})["count"].(float64)
+		}
+		assert.Equalf(t, counts, expected, "Expected %s facet '%v'. Got '%v'", facet, expected, counts)
+	}
+
+	// the ranges carry the bounds of the filters which select them
+	preptime := facets["preptime"].([]interface{})[1].(map[string]interface{})
+	assert.Equalf(t, preptime["min"], 15.0, "Expected a min of '15'. Got '%v'", preptime["min"])
+	assert.Equalf(t, preptime["max"], 30.0, "Expected a max of '30'. Got '%v'", preptime["max"])
+}
+
+func TestSearchWithoutFacets(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+
+	// facets are only counted (and the results enveloped) on request
+	mm := searchRecipes(t, map[string]string{"facets": "false"})
+	assert.Equalf(t, len(mm), 3, "Expected 3 recipes. Got '%v'", len(mm))
+
+	m, _ := getRecipesPage(t, "POST", "/v1/search/recipes?limit=2")
+	assert.NotContainsf(t, m, "facets", "Expected no 'facets'. Got '%v'", m["facets"])
+
+	req, err := http.NewRequest("POST", "/v1/search/recipes?facets=maybe", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
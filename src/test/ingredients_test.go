@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestGetIngredientsEmpty(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1/ingredients", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	body := response.Body.String()
+	assert.Equalf(t, body, "[]", "Expected an empty array. Got %s", body)
+}
+
+func TestGetIngredientsNonExistentRecipe(t *testing.T) {
+	clearTables()
+
+	req, err := http.NewRequest("GET", "/v1/recipes/11/ingredients", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestAddIngredientNoCredentials(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	payload := []byte(`{"quantity":2,"unit":"cup","item":"flour"}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/ingredients", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestAddIngredientWithCredentials(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	payload := []byte(`{"quantity":2,"unit":"cup","item":"flour","note":"sifted"}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/ingredients", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	// the ids are compared to 1.0 because JSON unmarshaling converts numbers to
+	//     floats (float64), when the target is a map[string]interface{}
+	assert.Equalf(t, m["ingredient_id"], 1.0, "Expected ingredient ID to be '1'. Got '%v'", m["ingredient_id"])
+	assert.Equalf(t, m["recipe_id"], 1.0, "Expected recipe ID to be '1'. Got '%v'", m["recipe_id"])
+	assert.Equalf(t, m["quantity"], 2.0, "Expected quantity to be '2'. Got '%v'", m["quantity"])
+	assert.Equalf(t, m["unit"], "cup", "Expected unit to be 'cup'. Got '%v'", m["unit"])
+	assert.Equalf(t, m["item"], "flour", "Expected item to be 'flour'. Got '%v'", m["item"])
+	assert.Equalf(t, m["note"], "sifted", "Expected note to be 'sifted'. Got '%v'", m["note"])
+}
+
+func TestAddIngredientWithInvalidPayload(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/ingredients", bytes.NewBufferString(`{"invalid json"}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	for payload, field := range map[string]string{
+		`{"quantity":-1,"unit":"cup","item":"flour"}`:            "quantity",
+		`{"quantity":1,"unit":"cup"}`:                            "item",
+		`{"quantity":1,"unit":"cup","item":"flour","brand":"x"}`: "brand",
+	} {
+		req, err := http.NewRequest("POST", "/v1/recipes/1/ingredients", bytes.NewBufferString(payload))
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		req.SetBasicAuth(authUser, authPassword)
+		response := executeRequest(req)
+
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+}
+
+func TestAddIngredientWithMismatchedID(t *testing.T) {
+	clearTables()
+
+	payload := []byte(`{"quantity":2,"unit":"cup","item":"flour"}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/99/ingredients", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestUpdateAndDeleteIngredient(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+	addIngredient(1, `{"quantity":2,"unit":"cup","item":"flour"}`)
+
+	payload := []byte(`{"quantity":3,"unit":"tbsp","item":"sugar"}`)
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/1/ingredients/1", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest (PUT): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1/ingredients/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["item"], "sugar", "Expected item to be 'sugar'. Got '%v'", m["item"])
+	assert.Equalf(t, m["quantity"], 3.0, "Expected quantity to be '3'. Got '%v'", m["quantity"])
+
+	// The ingredient does not belong to recipe 2
+	req, err = http.NewRequest("DELETE", "/v1/recipes/2/ingredients/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	req, err = http.NewRequest("DELETE", "/v1/recipes/1/ingredients/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1/ingredients/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (Second GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetRecipeWithIngredients(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addIngredient(1, `{"quantity":2,"unit":"cup","item":"flour"}`)
+	addIngredient(1, `{"quantity":1,"unit":"","item":"egg"}`)
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m struct {
+		Name        string                   `json:"name"`
+		Ingredients []map[string]interface{} `json:"ingredients"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	assert.Equalf(t, m.Name, "Recipe 0", "Expected recipe name to be 'Recipe 0'. Got '%v'", m.Name)
+	assert.Equalf(t, len(m.Ingredients), 2, "Expected '2' ingredients. Got '%v'", len(m.Ingredients))
+	assert.Equalf(t, m.Ingredients[1]["item"], "egg", "Expected second ingredient to be 'egg'. Got '%v'", m.Ingredients[1]["item"])
+}
+
+func TestDeleteRecipeDeletesIngredients(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addIngredient(1, `{"quantity":2,"unit":"cup","item":"flour"}`)
+
+	req, err := http.NewRequest("DELETE", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1/ingredients/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func addIngredient(recipe int, payload string) {
+	req, _ := http.NewRequest("POST", "/v1/recipes/"+strconv.Itoa(recipe)+"/ingredients", bytes.NewBufferString(payload))
+	req.SetBasicAuth(authUser, authPassword)
+	executeRequest(req)
+}
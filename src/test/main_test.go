@@ -3,8 +3,8 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -13,24 +13,44 @@ import (
 	"testing"
 	// local import
 	"application"
+	"recipes"
 )
 
 var app application.App
 
+var db *sqlx.DB
+
+var dbDriver string
+
 var authUser, authPassword string
 
 func TestMain(m *testing.M) {
 	authUser = os.Getenv("AUTH_USER")
 	authPassword = os.Getenv("AUTH_PASSWORD")
+	if authUser == "" {
+		authUser, authPassword = "chef", "bourdain"
+	}
+	dbDriver = os.Getenv("DB_DRIVER")
+	if dbDriver == "" && os.Getenv("POSTGRES_HOST") == "" {
+		// No database configured, so run against the in-memory store
+		dbDriver = "memory"
+	}
+	dbName := os.Getenv("POSTGRES_DB")
+	if dbDriver == recipes.SQLite {
+		dbName = ":memory:"
+	}
 	app = application.App{}
 	app.Initialize(
+		dbDriver,
 		os.Getenv("POSTGRES_HOST"),
 		os.Getenv("POSTGRES_USER"),
 		os.Getenv("POSTGRES_PASSWORD"),
-		os.Getenv("POSTGRES_DB"),
+		dbName,
 		authUser,
 		authPassword)
-	ensureTablesExist()
+	if store, ok := app.Store.(*recipes.SQLStore); ok {
+		db = store.DB
+	}
 	code := m.Run()
 	clearTables()
 	os.Exit(code)
@@ -512,20 +532,37 @@ func checkResponseCode(t *testing.T, expected, actual int) {
 	assert.Equalf(t, expected, actual, "Expected response code %d - Got %d", expected, actual)
 }
 
-func ensureTablesExist() {
-	if _, err := app.DB.Exec(recipesTableCreationQuery); err != nil {
-		log.Fatal(err)
+func clearTables() {
+	if db == nil {
+		app.Store = recipes.NewMemoryStore()
+		return
 	}
-	if _, err := app.DB.Exec(ratingsTableCreationQuery); err != nil {
-		log.Fatal(err)
+	if dbDriver == recipes.SQLite {
+		db.Exec("DELETE FROM recipes")
+		db.Exec("DELETE FROM recipe_ratings")
+		db.Exec("DELETE FROM reviews")
+		db.Exec("DELETE FROM tags")
+		db.Exec("DELETE FROM users")
+		db.Exec("DELETE FROM api_keys")
+		db.Exec("DELETE FROM sqlite_sequence WHERE name <> 'taxonomy_terms'")
+		return
 	}
-}
-
-func clearTables() {
-	app.DB.Exec("DELETE FROM recipes")
-	app.DB.Exec("ALTER SEQUENCE recipes_id_seq RESTART WITH 1")
-	app.DB.Exec("DELETE FROM recipe_ratings")
-	app.DB.Exec("ALTER SEQUENCE recipe_ratings_rating_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM recipes")
+	db.Exec("ALTER SEQUENCE recipes_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM recipe_ratings")
+	db.Exec("ALTER SEQUENCE recipe_ratings_rating_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM reviews")
+	db.Exec("ALTER SEQUENCE reviews_review_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM ingredients")
+	db.Exec("ALTER SEQUENCE ingredients_ingredient_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM steps")
+	db.Exec("ALTER SEQUENCE steps_step_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM tags")
+	db.Exec("ALTER SEQUENCE tags_tag_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM users")
+	db.Exec("ALTER SEQUENCE users_user_id_seq RESTART WITH 1")
+	db.Exec("DELETE FROM api_keys")
+	db.Exec("ALTER SEQUENCE api_keys_key_id_seq RESTART WITH 1")
 }
 
 func TestAddRating(t *testing.T) {
@@ -595,7 +632,7 @@ func TestAddRatingWithMismatchedID(t *testing.T) {
 	assert.Nilf(t, err, "Error on http.NewRequest (2nd POST): %s", err)
 	response = executeRequest(req)
 
-	checkResponseCode(t, http.StatusInternalServerError, response.Code)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
 }
 
 func TestAddRatingWithInvalidPayload(t *testing.T) {
@@ -667,8 +704,10 @@ func TestSearch(t *testing.T) {
 
 	payload = []byte(`{"rating":2}`)
 
+	// from another client (whose rating does not replace the first)
 	req, err = http.NewRequest("POST", "/v1/recipes/1/rating", bytes.NewBuffer(payload))
 	assert.Nilf(t, err, "Error on http.NewRequest (3rd POST): %s", err)
+	req.RemoteAddr = "192.0.2.2:1234"
 	response = executeRequest(req)
 
 	checkResponseCode(t, http.StatusCreated, response.Code)
@@ -836,8 +875,8 @@ func addRecipes(count int) {
 		count = 1
 	}
 	for i := 0; i < count; i++ {
-		app.DB.Exec("INSERT INTO recipes(name, preptime, difficulty, vegetarian) VALUES($1, $2, $3, $4)",
-			"Recipe "+strconv.Itoa(i), (i+1.0)*10, i%3+1, true)
+		r := recipes.Recipe{Name: "Recipe " + strconv.Itoa(i), PrepTime: float32(i+1.0) * 10, Difficulty: i%3 + 1, Vegetarian: true}
+		app.Store.CreateRecipe(&r)
 	}
 }
 
@@ -851,23 +890,6 @@ func addRecipeRatings(recipe int, count int) {
 }
 
 func addRecipeRating(recipe int, rating int) {
-	app.DB.Exec("INSERT INTO recipe_ratings(recipe_id, rating) VALUES($1, $2)", recipe, rating)
-}
-
-const recipesTableCreationQuery = `CREATE TABLE IF NOT EXISTS recipes
-(
-	id BIGSERIAL,
-	name TEXT NOT NULL UNIQUE,
-	preptime FLOAT(4) NOT NULL DEFAULT 0.0,
-	difficulty NUMERIC(1) NOT NULL CHECK (difficulty > 0) CHECK (difficulty < 4) DEFAULT 0,
-	vegetarian BOOLEAN NOT NULL DEFAULT false,
-	CONSTRAINT recipes_pkey PRIMARY KEY (id)
-)`
-
-const ratingsTableCreationQuery = `CREATE TABLE IF NOT EXISTS recipe_ratings
-(
-	recipe_id BIGINT REFERENCES recipes(id) ON DELETE CASCADE,
-	rating_id BIGSERIAL,
-	rating SMALLINT NOT NULL CHECK (rating > 0) CHECK (rating < 6) DEFAULT 0,
-	PRIMARY KEY (recipe_id, rating_id)
-)`
+	rr := recipes.RecipeRating{RecipeID: recipe, Rating: rating}
+	app.Store.SetRecipeRating(&rr)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	// local import
+	"application"
+	"recipes"
+)
+
+func TestMigrations(t *testing.T) {
+	// Always uses a fresh SQLite database, so as not to disturb the other tests
+	store, err := application.OpenStore(recipes.SQLite, "", "", "", ":memory:")
+	assert.Nilf(t, err, "Error on application.OpenStore: %s", err)
+	migrator := store.(recipes.Migrator)
+
+	status, err := migrator.MigrationStatus()
+	assert.Nilf(t, err, "Error on MigrationStatus: %s", err)
+	assert.Equalf(t, len(recipes.Migrations), len(status), "Expected '%d' migrations. Got '%d'", len(recipes.Migrations), len(status))
+	for _, ms := range status {
+		assert.Nilf(t, ms.AppliedAt, "Expected migration %d to be pending", ms.Version)
+	}
+
+	applied, err := migrator.MigrateUp()
+	assert.Nilf(t, err, "Error on MigrateUp: %s", err)
+	assert.Equalf(t, len(recipes.Migrations), len(applied), "Expected '%d' migrations to be applied. Got '%d'", len(recipes.Migrations), len(applied))
+
+	// Applying again should be a no-op
+	applied, err = migrator.MigrateUp()
+	assert.Nilf(t, err, "Error on second MigrateUp: %s", err)
+	assert.Equalf(t, 0, len(applied), "Expected no migrations to be applied. Got '%d'", len(applied))
+
+	r := recipes.Recipe{Name: "test recipe", PrepTime: 0.1, Difficulty: 2, Vegetarian: true}
+	assert.Nil(t, store.CreateRecipe(&r), "Expected to be able to create a recipe once migrated")
+
+	latest := recipes.Migrations[len(recipes.Migrations)-1]
+	reverted, err := migrator.MigrateDown()
+	assert.Nilf(t, err, "Error on MigrateDown: %s", err)
+	assert.Equalf(t, latest.Version, reverted.Version, "Expected migration %d to be reverted. Got %d", latest.Version, reverted.Version)
+
+	status, err = migrator.MigrationStatus()
+	assert.Nilf(t, err, "Error on MigrationStatus: %s", err)
+	assert.Nilf(t, status[len(status)-1].AppliedAt, "Expected migration %d to be pending", latest.Version)
+	assert.NotNilf(t, status[0].AppliedAt, "Expected migration %d to be applied", status[0].Version)
+
+	applied, err = migrator.MigrateUp()
+	assert.Nilf(t, err, "Error on MigrateUp after MigrateDown: %s", err)
+	assert.Equalf(t, 1, len(applied), "Expected '1' migration to be re-applied. Got '%d'", len(applied))
+}
+
+func TestMigrateCommand(t *testing.T) {
+	store, err := application.OpenStore(recipes.SQLite, "", "", "", ":memory:")
+	assert.Nilf(t, err, "Error on application.OpenStore: %s", err)
+
+	var out bytes.Buffer
+	assert.Nil(t, application.Migrate(&out, store, "up"), "Expected 'migrate up' to succeed")
+	assert.Containsf(t, out.String(), "Applied migration 1", "Expected migration 1 to be applied. Got '%s'", out.String())
+
+	out.Reset()
+	assert.Nil(t, application.Migrate(&out, store, "status"), "Expected 'migrate status' to succeed")
+	assert.NotContainsf(t, out.String(), "pending", "Expected no pending migrations. Got '%s'", out.String())
+
+	out.Reset()
+	assert.Nil(t, application.Migrate(&out, store, "down"), "Expected 'migrate down' to succeed")
+	assert.Containsf(t, out.String(), "Reverted migration", "Expected a migration to be reverted. Got '%s'", out.String())
+
+	assert.NotNil(t, application.Migrate(&out, store, "sideways"), "Expected an unknown command to fail")
+	assert.NotNil(t, application.Migrate(&out, recipes.NewMemoryStore(), "up"), "Expected the memory store to have no schema")
+}
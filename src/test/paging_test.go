@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"recipes"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestGetRecipesByCursor(t *testing.T) {
+	clearTables()
+	addRecipes(25)
+
+	// follow the next cursors from the first page to the last
+	names := []string{}
+	cursors := []string{}
+	m, _ := getRecipesPage(t, "GET", "/v1/recipes?limit=10&sort=preptime")
+	assert.Equalf(t, m["total"], 25.0, "Expected a total of '25'. Got '%v'", m["total"])
+	assert.NotContainsf(t, m, "prev", "Expected no 'prev' on the first page. Got '%v'", m["prev"])
+	for {
+		for _, r := range m["recipes"].([]interface{}) {
+			names = append(names, r.(map[string]interface{})["name"].(string))
+		}
+		next, ok := m["next"].(string)
+		if !ok {
+			break
+		}
+		cursors = append(cursors, next)
+		m, _ = getRecipesPage(t, "GET", "/v1/recipes?limit=10&cursor="+next)
+	}
+	assert.Equalf(t, len(cursors), 2, "Expected 3 pages. Got '%v'", len(cursors)+1)
+	expected := []string{}
+	for i := 0; i < 25; i++ {
+		expected = append(expected, "Recipe "+strconv.Itoa(i))
+	}
+	assert.Equalf(t, names, expected, "Expected recipes '%v'. Got '%v'", expected, names)
+
+	// and back again from the last page
+	m, _ = getRecipesPage(t, "GET", "/v1/recipes?limit=10&cursor="+m["prev"].(string))
+	page := m["recipes"].([]interface{})
+	if assert.Equalf(t, len(page), 10, "Expected 10 recipes. Got '%v'", len(page)) {
+		assert.Equalf(t, page[0].(map[string]interface{})["name"], "Recipe 10", "Expected 'Recipe 10'. Got '%v'", page[0])
+		assert.Equalf(t, page[9].(map[string]interface{})["name"], "Recipe 19", "Expected 'Recipe 19'. Got '%v'", page[9])
+	}
+	m, _ = getRecipesPage(t, "GET", "/v1/recipes?limit=10&cursor="+m["prev"].(string))
+	assert.NotContainsf(t, m, "prev", "Expected no 'prev' on the first page. Got '%v'", m["prev"])
+}
+
+func TestCursorIsStableAsRecipesAreAdded(t *testing.T) {
+	clearTables()
+	addRecipes(4)
+
+	m, _ := getRecipesPage(t, "GET", "/v1/recipes?limit=2")
+	app.Store.CreateRecipe(&recipes.Recipe{Name: "Recipe 00", Difficulty: 1})
+
+	// offset paging would repeat "Recipe 1", as "Recipe 00" sorts before it
+	m, _ = getRecipesPage(t, "GET", "/v1/recipes?limit=2&cursor="+m["next"].(string))
+	page := m["recipes"].([]interface{})
+	if assert.Equalf(t, len(page), 2, "Expected 2 recipes. Got '%v'", len(page)) {
+		assert.Equalf(t, page[0].(map[string]interface{})["name"], "Recipe 2", "Expected 'Recipe 2'. Got '%v'", page[0])
+	}
+	assert.Equalf(t, m["total"], 5.0, "Expected a total of '5'. Got '%v'", m["total"])
+}
+
+func TestSearchByCursorSortedByRating(t *testing.T) {
+	clearTables()
+	addRecipes(5)
+	// averages which cannot be represented exactly
+	for recipe, ratings := range map[int][]int{1: {3, 3, 4}, 2: {3, 3, 4}, 3: {4, 4, 3}, 4: {1, 2}} {
+		for _, rating := range ratings {
+			addRecipeRating(recipe, rating)
+		}
+	}
+
+	names := []string{}
+	path := "/v1/search/recipes?limit=1&sort=-avg_rating"
+	for path != "" {
+		m, header := getRecipesPage(t, "POST", path)
+		for _, r := range m["recipes"].([]interface{}) {
+			names = append(names, r.(map[string]interface{})["name"].(string))
+		}
+		// follow the Link header, which carries the sort
+		path = ""
+		if match := regexp.MustCompile(`<([^>]*)>; rel="next"`).FindStringSubmatch(header.Get("Link")); match != nil {
+			path = match[1]
+		}
+	}
+	// ties are broken by ID, in the same direction
+	expected := []string{"Recipe 2", "Recipe 1", "Recipe 0", "Recipe 3", "Recipe 4"}
+	assert.Equalf(t, names, expected, "Expected recipes '%v'. Got '%v'", expected, names)
+}
+
+func TestGetRecipesByStartAndCountLinks(t *testing.T) {
+	clearTables()
+	addRecipes(25)
+
+	req, err := http.NewRequest("GET", "/v1/recipes?start=10&count=5&sort=preptime", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// existing clients still receive a bare array
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	assert.Equalf(t, len(mm), 5, "Expected 5 recipes. Got '%v'", len(mm))
+	assert.Equalf(t, response.Header().Get("X-Total-Count"), "25", "Expected X-Total-Count '25'. Got '%v'", response.Header().Get("X-Total-Count"))
+	link := response.Header().Get("Link")
+	for _, expected := range []string{
+		`</v1/recipes?count=5&sort=preptime&start=0>; rel="first"`,
+		`</v1/recipes?count=5&sort=preptime&start=5>; rel="prev"`,
+		`</v1/recipes?count=5&sort=preptime&start=15>; rel="next"`,
+	} {
+		assert.Containsf(t, link, expected, "Expected Link '%s'. Got '%s'", expected, link)
+	}
+}
+
+func TestGetRecipesWithInvalidCursor(t *testing.T) {
+	clearTables()
+	addRecipes(3)
+
+	m, _ := getRecipesPage(t, "GET", "/v1/recipes?limit=1&sort=-name")
+	for _, query := range []string{
+		"limit=0",
+		"limit=101",
+		"cursor=not-a-cursor",
+		"cursor=eyJzIjoic2VydmluZ3MiLCJ2IjoxLCJpZCI6MX0", // {"s":"servings","v":1,"id":1}
+		"cursor=" + m["next"].(string) + "&sort=name",
+	} {
+		req, err := http.NewRequest("GET", "/v1/recipes?"+query, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		assert.Equalf(t, response.Code, http.StatusBadRequest, "Expected response code %d for '%s'. Got %d", http.StatusBadRequest, query, response.Code)
+	}
+}
+
+func getRecipesPage(t *testing.T, method, path string) (map[string]interface{}, http.Header) {
+	req, err := http.NewRequest(method, path, nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	return m, response.Header()
+}
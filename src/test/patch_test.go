@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestMergePatchRecipe(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+
+	// only the fields supplied are changed (plain JSON is a merge patch too)
+	for _, contentType := range []string{"application/merge-patch+json", "application/json", ""} {
+		response := patchRecipe(1, contentType, `{"name":"Recipe 1a","servings":4}`)
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var m map[string]interface{}
+		json.Unmarshal(response.Body.Bytes(), &m)
+		assert.Equalf(t, m["name"], "Recipe 1a", "Expected name 'Recipe 1a'. Got '%v'", m["name"])
+		assert.Equalf(t, m["servings"], 4.0, "Expected servings '4'. Got '%v'", m["servings"])
+		assert.Equalf(t, m["preptime"], 10.0, "Expected preptime '10' to be kept. Got '%v'", m["preptime"])
+		assert.Equalf(t, m["difficulty"], 1.0, "Expected difficulty '1' to be kept. Got '%v'", m["difficulty"])
+		assert.Equalf(t, m["vegetarian"], true, "Expected vegetarian 'true' to be kept. Got '%v'", m["vegetarian"])
+	}
+
+	// null removes a field (so resets it)
+	response := patchRecipe(1, "application/merge-patch+json", `{"servings":null}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["servings"], 0.0, "Expected servings '0'. Got '%v'", m["servings"])
+
+	for payload, code := range map[string]int{
+		`{"difficulty":null}`:      http.StatusUnprocessableEntity,
+		`{"preptime":"an hour"}`:   http.StatusUnprocessableEntity,
+		`{"name":"Recipe 1"}`:      http.StatusConflict,
+		`{"name":"Recipe 1"`:       http.StatusBadRequest,
+		`["not","a","recipe"]`:     http.StatusUnprocessableEntity,
+		`{"vegetarian":"perhaps"}`: http.StatusUnprocessableEntity,
+	} {
+		response := patchRecipe(1, "application/merge-patch+json", payload)
+		assert.Equalf(t, response.Code, code, "Expected response code %d for '%s'. Got %d", code, payload, response.Code)
+	}
+}
+
+func TestJSONPatchRecipe(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := patchRecipe(1, "application/json-patch+json", `[
+		{"op":"test","path":"/name","value":"Recipe 0"},
+		{"op":"replace","path":"/preptime","value":25},
+		{"op":"copy","from":"/difficulty","path":"/servings"},
+		{"op":"remove","path":"/vegetarian"}
+	]`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["name"], "Recipe 0", "Expected name 'Recipe 0' to be kept. Got '%v'", m["name"])
+	assert.Equalf(t, m["preptime"], 25.0, "Expected preptime '25'. Got '%v'", m["preptime"])
+	assert.Equalf(t, m["servings"], 1.0, "Expected servings '1'. Got '%v'", m["servings"])
+	assert.Equalf(t, m["vegetarian"], false, "Expected vegetarian 'false'. Got '%v'", m["vegetarian"])
+
+	for payload, code := range map[string]int{
+		`[{"op":"test","path":"/name","value":"Recipe 9"},{"op":"replace","path":"/preptime","value":1}]`: http.StatusConflict,
+		`[{"op":"replace","path":"/calories","value":100}]`:                                               http.StatusUnprocessableEntity,
+		`[{"op":"add","path":"/name/0","value":"X"}]`:                                                     http.StatusUnprocessableEntity,
+		`[{"op":"replace","path":"/difficulty","value":4}]`:                                               http.StatusUnprocessableEntity,
+		`[{"op":"frobnicate","path":"/name"}]`:                                                            http.StatusUnprocessableEntity,
+		`[{"op":"replace","path":"name","value":"X"}]`:                                                    http.StatusUnprocessableEntity,
+		`[{"op":"replace","path":"/name"}]`:                                                               http.StatusUnprocessableEntity,
+		`[{"op":"replace","value":"X"}]`:                                                                  http.StatusBadRequest,
+		`{"op":"replace","path":"/name","value":"X"}`:                                                     http.StatusBadRequest,
+	} {
+		response := patchRecipe(1, "application/json-patch+json", payload)
+		assert.Equalf(t, response.Code, code, "Expected response code %d for '%s'. Got %d", code, payload, response.Code)
+	}
+
+	// the operation at fault is reported against the member of the patch
+	for payload, field := range map[string]string{
+		`[{"op":"test","path":"/name","value":"Recipe 0"},{"op":"frobnicate","path":"/name"}]`: "/1/op",
+		`[{"op":"move","from":"/calories","path":"/name"}]`:                                    "/0/from",
+		`[{"op":"remove","path":"/ingredients/7"}]`:                                            "/0/path",
+	} {
+		response := patchRecipe(1, "application/json-patch+json", payload)
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+
+	// a failed patch changes nothing
+	req, _ := http.NewRequest("GET", "/v1/recipes/1", nil)
+	response = executeRequest(req)
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["preptime"], 25.0, "Expected preptime '25'. Got '%v'", m["preptime"])
+}
+
+func TestPatchRecipeWithUnsupportedType(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := patchRecipe(1, "text/plain", `{"name":"Recipe 1a"}`)
+	checkResponseCode(t, http.StatusUnsupportedMediaType, response.Code)
+	assert.Containsf(t, response.Header().Get("Accept-Patch"), "application/json-patch+json",
+		"Expected Accept-Patch to list the patch formats. Got '%s'", response.Header().Get("Accept-Patch"))
+}
+
+func patchRecipe(recipe int, contentType string, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("PATCH", "/v1/recipes/"+strconv.Itoa(recipe), bytes.NewBufferString(payload))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.SetBasicAuth(authUser, authPassword)
+	return executeRequest(req)
+}
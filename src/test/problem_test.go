@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorsAreProblems(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+
+	response := conditionalRequest("GET", "/v1/recipes/99", "", "", "")
+	p := checkProblem(t, response, http.StatusNotFound, "/problems/not-found")
+	assert.Equalf(t, p["title"], "Not found", "Expected title 'Not found'. Got '%v'", p["title"])
+	assert.Equalf(t, p["detail"], "Recipe not found", "Expected detail 'Recipe not found'. Got '%v'", p["detail"])
+
+	payload := `{"name":"Recipe 1","preptime":10,"difficulty":1,"vegetarian":true}`
+	response = conditionalRequest("POST", "/v1/recipes", "", "", payload)
+	p = checkProblem(t, response, http.StatusConflict, "/problems/duplicate")
+	assert.Equalf(t, p["detail"], "recipe name already exists", "Expected detail 'recipe name already exists'. Got '%v'", p["detail"])
+
+	// each store reports what is duplicated (not that a recipe name is)
+	response = conditionalRequest("POST", "/v1/courses", "", "", `{"name":"amuse-bouche"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var term map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &term)
+	response = conditionalRequest("POST", "/v1/courses", "", "", `{"name":"amuse-bouche"}`)
+	p = checkProblem(t, response, http.StatusConflict, "/problems/duplicate")
+	assert.Equalf(t, p["detail"], "term already exists", "Expected detail 'term already exists'. Got '%v'", p["detail"])
+	// (terms outlive clearTables)
+	response = conditionalRequest("DELETE", fmt.Sprintf("/v1/courses/%v", term["term_id"]), "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	credentials := `{"username":"julia","password":"password"}`
+	response = authRequest("/v1/auth/register", credentials)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	response = authRequest("/v1/auth/register", credentials)
+	p = checkProblem(t, response, http.StatusConflict, "/problems/duplicate")
+	assert.Equalf(t, p["detail"], "username already exists", "Expected detail 'username already exists'. Got '%v'", p["detail"])
+
+	response = conditionalRequest("DELETE", "/v1/recipes/1", "If-Match", `"7"`, "")
+	checkProblem(t, response, http.StatusPreconditionFailed, "/problems/modified")
+
+	// errors which are not about a resource are simply described by their status
+	response = conditionalRequest("GET", "/v1/recipes/Z", "", "", "")
+	p = checkProblem(t, response, http.StatusBadRequest, "about:blank")
+	assert.Equalf(t, p["title"], "Bad Request", "Expected title 'Bad Request'. Got '%v'", p["title"])
+
+	req, _ := http.NewRequest("DELETE", "/v1/recipes/1", nil)
+	response = executeRequest(req)
+	checkProblem(t, response, http.StatusUnauthorized, "about:blank")
+	assert.NotEmptyf(t, response.Header().Get("WWW-Authenticate"), "Expected a WWW-Authenticate header")
+}
+
+func TestInvalidPayloadsHaveFieldErrors(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	for payload, field := range map[string]string{
+		`{"name":" ","preptime":10,"difficulty":1}`:                       "name",
+		`{"name":"Soup","preptime":-1,"difficulty":1}`:                    "preptime",
+		`{"name":"Soup","preptime":10,"difficulty":4}`:                    "difficulty",
+		`{"name":"Soup","preptime":10,"difficulty":1,"servings":-2}`:      "servings",
+		`{"name":"Soup","preptime":10,"difficulty":1,"calories":100}`:     "calories",
+		`{"name":"Soup","preptime":"an hour","difficulty":1}`:             "preptime",
+		`{"name":"` + strings.Repeat("a", 201) + `","difficulty":1}`:      "name",
+		`{"name":"Soup","preptime":10,"difficulty":1,"vegetarian":"yes"}`: "vegetarian",
+	} {
+		for _, method := range []string{"POST", "PUT"} {
+			path := "/v1/recipes"
+			if method == "PUT" {
+				path += "/1"
+			}
+			response := conditionalRequest(method, path, "", "", payload)
+			p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+			checkFieldError(t, p, field)
+		}
+	}
+
+	for payload, field := range map[string]string{
+		`{"difficulty":0}`: "difficulty",
+		`{"calories":100}`: "calories",
+	} {
+		response := patchRecipe(1, "application/merge-patch+json", payload)
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+
+	for _, payload := range []string{`{"rating":0}`, `{"rating":6}`, `{}`, `{"rating":5,"stars":5}`} {
+		response := conditionalRequest("POST", "/v1/recipes/1/rating", "", "", payload)
+		checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+	}
+
+	// nothing invalid was stored
+	m, _ := getRecipesPage(t, "GET", "/v1/recipes?limit=10")
+	assert.Equalf(t, m["total"], 1.0, "Expected a total of '1'. Got '%v'", m["total"])
+	response := conditionalRequest("GET", "/v1/recipes/1", "", "", "")
+	var r map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &r)
+	assert.Equalf(t, r["name"], "Recipe 0", "Expected name 'Recipe 0'. Got '%v'", r["name"])
+	response = conditionalRequest("POST", "/v1/recipes/1/rating", "", "", `{"rating":5}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	json.Unmarshal(response.Body.Bytes(), &r)
+	assert.Equalf(t, r["rating_id"], 1.0, "Expected rating ID to be '1'. Got '%v'", r["rating_id"])
+}
+
+func checkProblem(t *testing.T, response *httptest.ResponseRecorder, status int, problemType string) map[string]interface{} {
+	checkResponseCode(t, status, response.Code)
+	contentType := response.Header().Get("Content-Type")
+	assert.Equalf(t, contentType, "application/problem+json", "Expected Content-Type 'application/problem+json'. Got '%v'", contentType)
+
+	var p map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &p)
+	assert.Equalf(t, p["type"], problemType, "Expected type '%v'. Got '%v'", problemType, p["type"])
+	assert.Equalf(t, p["status"], float64(status), "Expected status '%v'. Got '%v'", status, p["status"])
+	return p
+}
+
+func checkFieldError(t *testing.T, p map[string]interface{}, field string) {
+	errs, _ := p["errors"].([]interface{})
+	if assert.Equalf(t, len(errs), 1, "Expected 1 field error. Got '%v'", p["errors"]) {
+		fieldErr := errs[0].(map[string]interface{})
+		assert.Equalf(t, fieldErr["field"], field, "Expected an error for '%v'. Got '%v'", field, fieldErr["field"])
+		assert.NotEmptyf(t, fieldErr["message"], "Expected a message for '%v'", field)
+	}
+}
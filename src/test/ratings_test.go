@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRatingsAreReplaced(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+
+	// however often a user rates a recipe, they have only the one rating
+	for _, rating := range []string{"5", "5", "1"} {
+		response := bearerRequest("POST", "/v1/recipes/1/rating", julia, `{"rating":`+rating+`}`)
+		var m map[string]interface{}
+		json.Unmarshal(response.Body.Bytes(), &m)
+		assert.Equalf(t, m["rating_id"], 1.0, "Expected rating ID to be '1'. Got '%v'", m["rating_id"])
+		assert.Equalf(t, m["username"], "julia", "Expected username 'julia'. Got '%v'", m["username"])
+	}
+	response := bearerRequest("PUT", "/v1/recipes/1/rating", julia, `{"rating":2}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// as do anonymous clients
+	var ratingID interface{}
+	for _, rating := range []string{"5", "4"} {
+		response = clientRequest("POST", "/v1/recipes/1/rating", "192.0.2.1:1234", `{"rating":`+rating+`}`)
+		var m map[string]interface{}
+		json.Unmarshal(response.Body.Bytes(), &m)
+		if ratingID == nil {
+			ratingID = m["rating_id"]
+		}
+		assert.Equalf(t, m["rating_id"], ratingID, "Expected rating ID to be '%v'. Got '%v'", ratingID, m["rating_id"])
+		assert.NotContainsf(t, m, "username", "Expected no 'username'. Got '%v'", m["username"])
+	}
+	response = clientRequest("POST", "/v1/recipes/1/rating", "192.0.2.2:1234", `{"rating":3}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	m, _ := getRecipesPage(t, "POST", "/v1/search/recipes?limit=10")
+	rr := m["recipes"].([]interface{})[0].(map[string]interface{})
+	assert.Equalf(t, rr["rating_count"], 3.0, "Expected a rating count of '3'. Got '%v'", rr["rating_count"])
+	assert.Equalf(t, rr["avg_rating"], 3.0, "Expected an average rating of '3'. Got '%v'", rr["avg_rating"])
+
+	// anyone presenting credentials must present valid ones
+	response = bearerRequest("POST", "/v1/recipes/1/rating", "invalid", `{"rating":5}`)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestOwnRating(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+
+	response := bearerRequest("GET", "/v1/recipes/1/rating", julia, "")
+	checkProblem(t, response, http.StatusNotFound, "/problems/not-found")
+
+	bearerRequest("POST", "/v1/recipes/1/rating", julia, `{"rating":4}`)
+	clientRequest("POST", "/v1/recipes/1/rating", "192.0.2.1:1234", `{"rating":1}`)
+	response = bearerRequest("GET", "/v1/recipes/1/rating", julia, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["rating"], 4.0, "Expected rating '4'. Got '%v'", m["rating"])
+	response = clientRequest("GET", "/v1/recipes/1/rating", "192.0.2.1:1234", "")
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["rating"], 1.0, "Expected rating '1'. Got '%v'", m["rating"])
+
+	// only your own rating is withdrawn
+	response = bearerRequest("DELETE", "/v1/recipes/1/rating", julia, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = bearerRequest("DELETE", "/v1/recipes/1/rating", julia, "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	response = clientRequest("GET", "/v1/recipes/1/rating", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// after which a rating is new again
+	response = bearerRequest("POST", "/v1/recipes/1/rating", julia, `{"rating":5}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	response = clientRequest("GET", "/v1/recipes/99/rating", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetRatings(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+	bearerRequest("POST", "/v1/recipes/1/rating", julia, `{"rating":4}`)
+	clientRequest("POST", "/v1/recipes/1/rating", "192.0.2.1:1234", `{"rating":2}`)
+
+	response := conditionalRequest("GET", "/v1/recipes/1/ratings", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var ratings []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &ratings)
+	assert.Equalf(t, len(ratings), 2, "Expected 2 ratings. Got '%v'", len(ratings))
+	assert.Equalf(t, ratings[0]["username"], "julia", "Expected username 'julia'. Got '%v'", ratings[0]["username"])
+	assert.Equalf(t, ratings[1]["rating"], 2.0, "Expected rating '2'. Got '%v'", ratings[1]["rating"])
+	assert.NotContainsf(t, ratings[1], "rater", "Expected no 'rater'. Got '%v'", ratings[1]["rater"])
+
+	response = conditionalRequest("GET", "/v1/recipes/99/ratings", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+// clientRequest makes an anonymous request from a client at the address given.
+func clientRequest(method, path, remoteAddr, payload string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, strings.NewReader(payload))
+	req.RemoteAddr = remoteAddr
+	return executeRequest(req)
+}
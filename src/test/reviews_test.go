@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAddReview(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+	viewer := registerUser(t, "viewer", "viewer")
+
+	response := bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":4,"text":"Brown the onions well"}`)
+	checkResponseCode(t, http.StatusCreated, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["review_id"], 1.0, "Expected review ID to be '1'. Got '%v'", m["review_id"])
+	assert.Equalf(t, m["author"], "julia", "Expected author 'julia'. Got '%v'", m["author"])
+	assert.Equalf(t, m["flag_count"], 0.0, "Expected a flag count of '0'. Got '%v'", m["flag_count"])
+
+	for payload, field := range map[string]string{
+		`{"rating":6,"text":"Too good"}`:                "rating",
+		`{"rating":4,"text":" "}`:                       "text",
+		`{"rating":4,"text":"Fine","author":"jacques"}`: "author",
+	} {
+		response = bearerRequest("POST", "/v1/recipes/1/reviews", julia, payload)
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+
+	payload := `{"rating":4,"text":"Lovely"}`
+	response = bearerRequest("POST", "/v1/recipes/1/reviews", viewer, payload)
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = clientRequest("POST", "/v1/recipes/1/reviews", "192.0.2.1:1234", payload)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+	response = bearerRequest("POST", "/v1/recipes/99/reviews", julia, payload)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	response = conditionalRequest("GET", "/v1/recipes/1/reviews/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["text"], "Brown the onions well", "Expected text 'Brown the onions well'. Got '%v'", m["text"])
+}
+
+func TestGetReviews(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+	for i := 1; i <= 15; i++ {
+		response := bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":3,"text":"Review `+strconv.Itoa(i)+`"}`)
+		checkResponseCode(t, http.StatusCreated, response.Code)
+	}
+
+	// newest first, ten at a time
+	response := clientRequest("GET", "/v1/recipes/1/reviews", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var list struct {
+		Reviews []map[string]interface{} `json:"reviews"`
+		Total   int                      `json:"total"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &list)
+	assert.Equalf(t, list.Total, 15, "Expected a total of '15'. Got '%v'", list.Total)
+	assert.Equalf(t, len(list.Reviews), 10, "Expected 10 reviews. Got '%v'", len(list.Reviews))
+	assert.Equalf(t, list.Reviews[0]["text"], "Review 15", "Expected 'Review 15' first. Got '%v'", list.Reviews[0]["text"])
+	assert.Equalf(t, response.Header().Get("X-Total-Count"), "15", "Expected X-Total-Count '15'. Got '%v'", response.Header().Get("X-Total-Count"))
+	link := response.Header().Get("Link")
+	assert.Truef(t, strings.Contains(link, `</v1/recipes/1/reviews?limit=10&start=10>; rel="next"`), "Expected a next link. Got '%v'", link)
+
+	response = clientRequest("GET", "/v1/recipes/1/reviews?start=10&limit=10", "192.0.2.1:1234", "")
+	json.Unmarshal(response.Body.Bytes(), &list)
+	assert.Equalf(t, len(list.Reviews), 5, "Expected 5 reviews. Got '%v'", len(list.Reviews))
+	assert.Equalf(t, list.Reviews[4]["text"], "Review 1", "Expected 'Review 1' last. Got '%v'", list.Reviews[4]["text"])
+	link = response.Header().Get("Link")
+	assert.NotContainsf(t, link, `rel="next"`, "Expected no next link. Got '%v'", link)
+
+	for _, query := range []string{"?limit=0", "?limit=101", "?start=-1", "?start=first"} {
+		response = clientRequest("GET", "/v1/recipes/1/reviews"+query, "192.0.2.1:1234", "")
+		assert.Equalf(t, response.Code, http.StatusBadRequest, "Expected response code %d for '%s'. Got %d", http.StatusBadRequest, query, response.Code)
+	}
+	response = clientRequest("GET", "/v1/recipes/99/reviews", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestModifyReview(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+	jacques := registerUser(t, "jacques", "")
+	editor := registerUser(t, "marcella", "editor")
+	bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":4,"text":"Good"}`)
+	bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":2,"text":"Too salty"}`)
+
+	// only the author may change a review
+	payload := `{"rating":5,"text":"Even better the next day"}`
+	for token, code := range map[string]int{jacques: http.StatusForbidden, editor: http.StatusForbidden, julia: http.StatusOK} {
+		response := bearerRequest("PUT", "/v1/recipes/1/reviews/1", token, payload)
+		checkResponseCode(t, code, response.Code)
+	}
+	response := conditionalRequest("GET", "/v1/recipes/1/reviews/1", "", "", "")
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["rating"], 5.0, "Expected rating '5'. Got '%v'", m["rating"])
+	assert.NotEqualf(t, m["updated_at"], m["created_at"], "Expected 'updated_at' to change. Got '%v'", m["updated_at"])
+
+	// while editors may also delete it
+	response = bearerRequest("DELETE", "/v1/recipes/1/reviews/1", jacques, "")
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = bearerRequest("DELETE", "/v1/recipes/1/reviews/1", julia, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = bearerRequest("DELETE", "/v1/recipes/1/reviews/2", editor, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = bearerRequest("DELETE", "/v1/recipes/1/reviews/2", editor, "")
+	checkProblem(t, response, http.StatusNotFound, "/problems/not-found")
+}
+
+func TestModerateReviews(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	julia := registerUser(t, "julia", "")
+	jacques := registerUser(t, "jacques", "")
+	editor := registerUser(t, "marcella", "editor")
+	bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":1,"text":"Buy my cookbook instead"}`)
+	bearerRequest("POST", "/v1/recipes/1/reviews", julia, `{"rating":5,"text":"Wonderful"}`)
+
+	// each user's flag counts once
+	var m map[string]interface{}
+	for _, token := range []string{jacques, jacques, editor} {
+		response := bearerRequest("POST", "/v1/recipes/1/reviews/1/flag", token, "")
+		checkResponseCode(t, http.StatusOK, response.Code)
+		json.Unmarshal(response.Body.Bytes(), &m)
+	}
+	assert.Equalf(t, m["flag_count"], 2.0, "Expected a flag count of '2'. Got '%v'", m["flag_count"])
+	response := clientRequest("POST", "/v1/recipes/1/reviews/1/flag", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+
+	// only admins hide reviews, which only editors and admins then see
+	response = bearerRequest("POST", "/v1/recipes/1/reviews/1/hide", editor, "")
+	checkResponseCode(t, http.StatusForbidden, response.Code)
+	response = conditionalRequest("POST", "/v1/recipes/1/reviews/1/hide", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["hidden"], true, "Expected the review to be hidden. Got '%v'", m["hidden"])
+
+	var list struct {
+		Total int `json:"total"`
+	}
+	for token, total := range map[string]int{jacques: 1, editor: 2} {
+		response = bearerRequest("GET", "/v1/recipes/1/reviews", token, "")
+		json.Unmarshal(response.Body.Bytes(), &list)
+		assert.Equalf(t, list.Total, total, "Expected a total of '%v'. Got '%v'", total, list.Total)
+	}
+	response = clientRequest("GET", "/v1/recipes/1/reviews/1", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	response = bearerRequest("PUT", "/v1/recipes/1/reviews/1", julia, `{"rating":1,"text":"Buy it!"}`)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	response = bearerRequest("GET", "/v1/recipes/1/reviews/1", editor, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	response = conditionalRequest("POST", "/v1/recipes/1/reviews/1/unhide", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = clientRequest("GET", "/v1/recipes/1/reviews/1", "192.0.2.1:1234", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	response = conditionalRequest("POST", "/v1/recipes/1/reviews/99/hide", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
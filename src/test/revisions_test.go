@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestRecipeRevisions(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	response := conditionalRequest("PUT", "/v1/recipes/1", "", "",
+		`{"name":"Recipe 0a","preptime":10,"difficulty":2,"vegetarian":true}`)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// newest first, each recording who made which changes
+	revisions := getRevisions(t, "/v1/recipes/1/revisions")
+	assert.Equalf(t, len(revisions), 2, "Expected 2 revisions. Got '%v'", len(revisions))
+	latest := revisions[0].(map[string]interface{})
+	assert.Equalf(t, latest["revision"], 2.0, "Expected revision '2'. Got '%v'", latest["revision"])
+	assert.Equalf(t, latest["author"], authUser, "Expected author '%v'. Got '%v'", authUser, latest["author"])
+	assert.Equalf(t, latest["changes"], map[string]interface{}{
+		"name":       map[string]interface{}{"from": "Recipe 0", "to": "Recipe 0a"},
+		"difficulty": map[string]interface{}{"from": 1.0, "to": 2.0},
+	}, "Expected name and difficulty changes. Got '%v'", latest["changes"])
+	first := revisions[1].(map[string]interface{})
+	changes := first["changes"].(map[string]interface{})
+	assert.Equalf(t, changes["name"], map[string]interface{}{"from": nil, "to": "Recipe 0"},
+		"Expected the name to be created. Got '%v'", changes["name"])
+
+	req, _ := http.NewRequest("GET", "/v1/recipes/1/revisions/1", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	recipe := m["recipe"].(map[string]interface{})
+	assert.Equalf(t, recipe["name"], "Recipe 0", "Expected name 'Recipe 0'. Got '%v'", recipe["name"])
+	assert.Equalf(t, recipe["version"], 1.0, "Expected version '1'. Got '%v'", recipe["version"])
+
+	for path, code := range map[string]int{
+		"/v1/recipes/1/revisions/3": http.StatusNotFound,
+		"/v1/recipes/2/revisions/1": http.StatusNotFound,
+		"/v1/recipes/1/revisions/a": http.StatusBadRequest,
+		"/v1/recipes/2/revisions":   http.StatusNotFound,
+	} {
+		req, _ := http.NewRequest("GET", path, nil)
+		response := executeRequest(req)
+		checkResponseCode(t, code, response.Code)
+	}
+}
+
+func TestRestoreRecipeRevision(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	conditionalRequest("PATCH", "/v1/recipes/1", "", "", `{"name":"Recipe 0a","servings":4}`)
+
+	req, _ := http.NewRequest("POST", "/v1/recipes/1/revisions/1/restore", nil)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+
+	response = conditionalRequest("POST", "/v1/recipes/1/revisions/1/restore", "If-Match", `"1"`, "")
+	checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+
+	response = conditionalRequest("POST", "/v1/recipes/1/revisions/1/restore", "If-Match", `"2"`, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["name"], "Recipe 0", "Expected name 'Recipe 0'. Got '%v'", m["name"])
+	assert.Equalf(t, m["servings"], 0.0, "Expected servings '0'. Got '%v'", m["servings"])
+	assert.Equalf(t, m["version"], 3.0, "Expected version '3'. Got '%v'", m["version"])
+
+	// restoring is itself a revision, so may be undone
+	revisions := getRevisions(t, "/v1/recipes/1/revisions")
+	assert.Equalf(t, len(revisions), 3, "Expected 3 revisions. Got '%v'", len(revisions))
+	latest := revisions[0].(map[string]interface{})
+	assert.Equalf(t, latest["changes"], map[string]interface{}{
+		"name":     map[string]interface{}{"from": "Recipe 0a", "to": "Recipe 0"},
+		"servings": map[string]interface{}{"from": 4.0, "to": 0.0},
+	}, "Expected name and servings changes. Got '%v'", latest["changes"])
+
+	response = conditionalRequest("POST", "/v1/recipes/1/revisions/9/restore", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func getRevisions(t *testing.T, path string) []interface{} {
+	req, err := http.NewRequest("GET", path, nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var revisions []interface{}
+	json.Unmarshal(response.Body.Bytes(), &revisions)
+	return revisions
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestGetRecipeScaled(t *testing.T) {
+	clearTables()
+
+	payload := []byte(`{"name":"test recipe","preptime":0.1,"difficulty":2,"vegetarian":true,"servings":3}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest (POST): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	addIngredient(1, `{"quantity":1,"unit":"cup","item":"flour"}`)
+	addIngredient(1, `{"quantity":2,"unit":"","item":"eggs"}`)
+	addIngredient(1, `{"quantity":100,"unit":"grams","item":"butter"}`)
+	addIngredient(1, `{"quantity":0.125,"unit":"tsp","item":"salt"}`)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1?servings=2", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m struct {
+		Servings    int `json:"servings"`
+		Ingredients []struct {
+			Quantity float64 `json:"quantity"`
+		} `json:"ingredients"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	assert.Equalf(t, m.Servings, 2, "Expected servings to be '2'. Got '%v'", m.Servings)
+	assert.Equalf(t, len(m.Ingredients), 4, "Expected '4' ingredients. Got '%v'", len(m.Ingredients))
+	// 2/3 of a cup is rounded to the nearest eighth
+	assert.Equalf(t, m.Ingredients[0].Quantity, 0.625, "Expected '0.625' cups of flour. Got '%v'", m.Ingredients[0].Quantity)
+	// 4/3 of an egg is rounded to the nearest quarter
+	assert.Equalf(t, m.Ingredients[1].Quantity, 1.25, "Expected '1.25' eggs. Got '%v'", m.Ingredients[1].Quantity)
+	// metric units are rounded to 3 significant figures
+	assert.Equalf(t, m.Ingredients[2].Quantity, 66.7, "Expected '66.7' grams of butter. Got '%v'", m.Ingredients[2].Quantity)
+	// small quantities are never rounded away
+	assert.Equalf(t, m.Ingredients[3].Quantity, 0.125, "Expected '0.125' tsp of salt. Got '%v'", m.Ingredients[3].Quantity)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1?servings=6", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m.Ingredients[0].Quantity, 2.0, "Expected '2' cups of flour. Got '%v'", m.Ingredients[0].Quantity)
+	assert.Equalf(t, m.Ingredients[2].Quantity, 200.0, "Expected '200' grams of butter. Got '%v'", m.Ingredients[2].Quantity)
+
+	// The stored recipe is unchanged
+	req, err = http.NewRequest("GET", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m.Servings, 3, "Expected servings to be '3'. Got '%v'", m.Servings)
+	assert.Equalf(t, m.Ingredients[0].Quantity, 1.0, "Expected '1' cup of flour. Got '%v'", m.Ingredients[0].Quantity)
+}
+
+func TestGetRecipeScaledWithInvalidServings(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	for _, servings := range []string{"a", "0", "-2"} {
+		req, err := http.NewRequest("GET", "/v1/recipes/1?servings="+servings, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		checkResponseCode(t, http.StatusBadRequest, response.Code)
+	}
+
+	// The recipe does not specify its servings
+	req, err := http.NewRequest("GET", "/v1/recipes/1?servings=4", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
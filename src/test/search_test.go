@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	// local import
+	"recipes"
+)
+
+func TestSearchText(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+
+	mm := searchRecipes(t, map[string]string{"q": "Tomatoes"})
+	assert.Equalf(t, len(mm), 2, "Expected 2 recipes. Got '%v'", len(mm))
+	if len(mm) == 2 {
+		// a match in the name ranks above a match in the ingredients
+		assert.Equalf(t, mm[0]["name"], "Tomato Soup", "Expected 'Tomato Soup' first. Got '%v'", mm[0]["name"])
+		assert.Equalf(t, mm[1]["name"], "Pasta al Pomodoro", "Expected 'Pasta al Pomodoro' second. Got '%v'", mm[1]["name"])
+		assert.Truef(t, mm[0]["relevance"].(float64) > mm[1]["relevance"].(float64),
+			"Expected decreasing relevance. Got '%v' and '%v'", mm[0]["relevance"], mm[1]["relevance"])
+		assert.Containsf(t, mm[0], "avg_rating", "Expected 'avg_rating' alongside 'relevance'. Got '%v'", mm[0])
+	}
+
+	// every word must match, in any part of the recipe
+	mm = searchRecipes(t, map[string]string{"q": "tomato boiling"})
+	assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm))
+	if len(mm) == 1 {
+		assert.Equalf(t, mm[0]["name"], "Pasta al Pomodoro", "Expected 'Pasta al Pomodoro'. Got '%v'", mm[0]["name"])
+	}
+
+	mm = searchRecipes(t, map[string]string{"q": "baked"})
+	assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm))
+	if len(mm) == 1 {
+		assert.Equalf(t, mm[0]["name"], "Chocolate Cake", "Expected 'Chocolate Cake'. Got '%v'", mm[0]["name"])
+	}
+
+	mm = searchRecipes(t, map[string]string{"q": "marmalade"})
+	assert.Equalf(t, len(mm), 0, "Expected no recipes. Got '%v'", len(mm))
+}
+
+func TestSearchTextWithPreptime(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+
+	mm := searchRecipes(t, map[string]string{"q": "tomato", "preptime": "25"})
+	assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm))
+	if len(mm) == 1 {
+		assert.Equalf(t, mm[0]["name"], "Tomato Soup", "Expected 'Tomato Soup'. Got '%v'", mm[0]["name"])
+	}
+}
+
+func TestSearchWithoutTextHasNoRelevance(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+
+	mm := searchRecipes(t, map[string]string{})
+	assert.Equalf(t, len(mm), 3, "Expected 3 recipes. Got '%v'", len(mm))
+	for _, m := range mm {
+		assert.NotContainsf(t, m, "relevance", "Expected no 'relevance'. Got '%v'", m)
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	clearTables()
+	addRecipes(6)
+	// Recipe 0 (10 minutes, difficulty 1) rated 5 and 4 (by two raters)
+	addRecipeRating(1, 5)
+	addRecipeRating(1, 4)
+	// Recipe 1 (20 minutes, difficulty 2) rated 2
+	addRecipeRating(2, 2)
+	// Recipe 2 (30 minutes, difficulty 3) rated 5
+	addRecipeRating(3, 5)
+	r := recipes.Recipe{ID: 4, Name: "Recipe 3", PrepTime: 40, Difficulty: 1, Vegetarian: false}
+	app.Store.UpdateRecipe(&r)
+
+	for _, tc := range []struct {
+		fields   map[string]string
+		expected []string
+	}{
+		{map[string]string{"min_preptime": "20", "max_preptime": "40"}, []string{"Recipe 1", "Recipe 2", "Recipe 3"}},
+		{map[string]string{"preptime": "30"}, []string{"Recipe 0", "Recipe 1"}},
+		{map[string]string{"difficulty": "1,3"}, []string{"Recipe 0", "Recipe 2", "Recipe 3", "Recipe 5"}},
+		{map[string]string{"vegetarian": "false"}, []string{"Recipe 3"}},
+		{map[string]string{"vegetarian": "true", "difficulty": "1"}, []string{"Recipe 0"}},
+		{map[string]string{"min_avg_rating": "4.5"}, []string{"Recipe 0", "Recipe 2"}},
+		{map[string]string{"min_avg_rating": "4", "min_rating_count": "2"}, []string{"Recipe 0"}},
+		{map[string]string{"min_rating_count": "1", "max_preptime": "20"}, []string{"Recipe 0", "Recipe 1"}},
+	} {
+		mm := searchRecipes(t, tc.fields)
+		names := []string{}
+		for _, m := range mm {
+			names = append(names, m["name"].(string))
+		}
+		assert.Equalf(t, names, tc.expected, "Expected recipes '%v' for '%v'. Got '%v'", tc.expected, tc.fields, names)
+	}
+
+	mm := searchRecipes(t, map[string]string{"min_rating_count": "2"})
+	if assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm)) {
+		assert.Equalf(t, mm[0]["avg_rating"], 4.5, "Expected an average rating of '4.5'. Got '%v'", mm[0]["avg_rating"])
+		assert.Equalf(t, mm[0]["rating_count"], 2.0, "Expected a rating count of '2'. Got '%v'", mm[0]["rating_count"])
+	}
+}
+
+func TestSearchWithInvalidFilters(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	for _, fields := range []map[string]string{
+		{"min_preptime": "soon"},
+		{"max_preptime": "-1"},
+		{"min_preptime": "30", "max_preptime": "20"},
+		{"preptime": "NaN"},
+		{"difficulty": "4"},
+		{"difficulty": "1,easy"},
+		{"vegetarian": "maybe"},
+		{"min_avg_rating": "6"},
+		{"min_rating_count": "1.5"},
+		{"count": "ten"},
+		{"start": "first"},
+	} {
+		var bb bytes.Buffer
+		mw := multipart.NewWriter(&bb)
+		for name, value := range fields {
+			mw.WriteField(name, value)
+		}
+		mw.Close()
+
+		req, err := http.NewRequest("POST", "/v1/search/recipes", &bb)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		response := executeRequest(req)
+
+		assert.Equalf(t, response.Code, http.StatusBadRequest, "Expected response code %d for '%v'. Got %d", http.StatusBadRequest, fields, response.Code)
+	}
+}
+
+func TestGetRecipesWithFilters(t *testing.T) {
+	clearTables()
+	addRecipes(6)
+
+	req, err := http.NewRequest("GET", "/v1/recipes?difficulty=2&max_preptime=30", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	if assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm)) {
+		assert.Equalf(t, mm[0]["name"], "Recipe 1", "Expected 'Recipe 1'. Got '%v'", mm[0]["name"])
+	}
+
+	req, err = http.NewRequest("GET", "/v1/recipes?vegetarian=sometimes", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}
+
+func addSearchableRecipes() {
+	for _, payload := range []string{
+		`{"name":"Tomato Soup","preptime":20,"difficulty":1,"vegetarian":true}`,
+		`{"name":"Pasta al Pomodoro","preptime":30,"difficulty":2,"vegetarian":true}`,
+		`{"name":"Chocolate Cake","preptime":60,"difficulty":3,"vegetarian":true}`,
+	} {
+		req, _ := http.NewRequest("POST", "/v1/recipes", bytes.NewBufferString(payload))
+		req.SetBasicAuth(authUser, authPassword)
+		executeRequest(req)
+	}
+	addIngredient(1, `{"quantity":6,"unit":"","item":"ripe tomatoes"}`)
+	addIngredient(1, `{"quantity":1,"unit":"","item":"onion"}`)
+	addStep(1, `{"text":"Simmer gently for 20 minutes"}`)
+	addIngredient(2, `{"quantity":400,"unit":"g","item":"spaghetti"}`)
+	addIngredient(2, `{"quantity":1,"unit":"can","item":"chopped tomatoes"}`)
+	addStep(2, `{"text":"Boil the pasta"}`)
+	addIngredient(3, `{"quantity":200,"unit":"g","item":"flour"}`)
+	addStep(3, `{"text":"Bake until risen"}`)
+}
+
+func searchRecipes(t *testing.T, fields map[string]string) []map[string]interface{} {
+	var bb bytes.Buffer
+	mw := multipart.NewWriter(&bb)
+	for name, value := range fields {
+		mw.WriteField(name, value)
+	}
+	mw.Close()
+
+	req, err := http.NewRequest("POST", "/v1/search/recipes", &bb)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	return mm
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestGetRecipesSorted(t *testing.T) {
+	clearTables()
+	addRecipes(4)
+
+	for sort, expected := range map[string][]string{
+		"":            {"Recipe 0", "Recipe 1", "Recipe 2", "Recipe 3"},
+		"-name":       {"Recipe 3", "Recipe 2", "Recipe 1", "Recipe 0"},
+		"-preptime":   {"Recipe 3", "Recipe 2", "Recipe 1", "Recipe 0"},
+		"difficulty":  {"Recipe 0", "Recipe 3", "Recipe 1", "Recipe 2"},
+		"-difficulty": {"Recipe 2", "Recipe 1", "Recipe 3", "Recipe 0"},
+		"created_at":  {"Recipe 0", "Recipe 1", "Recipe 2", "Recipe 3"},
+		"-created_at": {"Recipe 3", "Recipe 2", "Recipe 1", "Recipe 0"},
+	} {
+		req, err := http.NewRequest("GET", "/v1/recipes?sort="+sort, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var mm []map[string]interface{}
+		json.Unmarshal(response.Body.Bytes(), &mm)
+		names := []string{}
+		for _, m := range mm {
+			names = append(names, m["name"].(string))
+		}
+		assert.Equalf(t, names, expected, "Expected recipes '%v' for sort '%s'. Got '%v'", expected, sort, names)
+	}
+}
+
+func TestSearchSortedByRating(t *testing.T) {
+	clearTables()
+	addRecipes(4)
+	addRecipeRating(1, 3)
+	addRecipeRating(2, 5)
+	addRecipeRating(3, 3)
+	addRecipeRating(3, 3)
+
+	for sort, expected := range map[string][]string{
+		// ties are broken by ID, in the same direction
+		"-avg_rating":   {"Recipe 1", "Recipe 2", "Recipe 0", "Recipe 3"},
+		"avg_rating":    {"Recipe 3", "Recipe 0", "Recipe 2", "Recipe 1"},
+		"-rating_count": {"Recipe 2", "Recipe 1", "Recipe 0", "Recipe 3"},
+	} {
+		mm := searchRecipes(t, map[string]string{"sort": sort})
+		names := []string{}
+		for _, m := range mm {
+			names = append(names, m["name"].(string))
+		}
+		assert.Equalf(t, names, expected, "Expected recipes '%v' for sort '%s'. Got '%v'", expected, sort, names)
+	}
+
+	// sorting applies before paging
+	mm := searchRecipes(t, map[string]string{"sort": "-avg_rating", "count": "1", "start": "1"})
+	if assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm)) {
+		assert.Equalf(t, mm[0]["name"], "Recipe 2", "Expected 'Recipe 2'. Got '%v'", mm[0]["name"])
+	}
+}
+
+func TestGetRecipesWithInvalidSort(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+
+	for _, sort := range []string{"servings", "name%3BDROP%20TABLE%20recipes", "--name", "name%20DESC"} {
+		req, err := http.NewRequest("GET", "/v1/recipes?sort="+sort, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		assert.Equalf(t, response.Code, http.StatusBadRequest, "Expected response code %d for sort '%s'. Got %d", http.StatusBadRequest, sort, response.Code)
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestAddStepNoCredentials(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	payload := []byte(`{"text":"Preheat the oven"}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/steps", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestAddStepWithCredentials(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addStep(1, `{"text":"Preheat the oven"}`)
+
+	payload := []byte(`{"text":"Bake","duration":25,"timer":1500}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/steps", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	// numbers are compared to floats because JSON unmarshaling converts numbers to
+	//     floats (float64), when the target is a map[string]interface{}
+	assert.Equalf(t, m["step_id"], 2.0, "Expected step ID to be '2'. Got '%v'", m["step_id"])
+	assert.Equalf(t, m["position"], 2.0, "Expected position to be '2'. Got '%v'", m["position"])
+	assert.Equalf(t, m["text"], "Bake", "Expected text to be 'Bake'. Got '%v'", m["text"])
+	assert.Equalf(t, m["duration"], 25.0, "Expected duration to be '25'. Got '%v'", m["duration"])
+	assert.Equalf(t, m["timer"], 1500.0, "Expected timer to be '1500'. Got '%v'", m["timer"])
+}
+
+func TestAddStepWithInvalidPayload(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/steps", bytes.NewBufferString(`{"invalid json"}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+
+	for payload, field := range map[string]string{
+		`{"text":""}`:                   "text",
+		`{"text":"Bake","timer":-1}`:    "timer",
+		`{"text":"Bake","oven":"hot"}`:  "oven",
+		`{"text":"Bake","timer":"ten"}`: "timer",
+	} {
+		req, err := http.NewRequest("POST", "/v1/recipes/1/steps", bytes.NewBufferString(payload))
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		req.SetBasicAuth(authUser, authPassword)
+		response := executeRequest(req)
+
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+}
+
+func TestUpdateStep(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addStep(1, `{"text":"Preheat the oven"}`)
+	addStep(1, `{"text":"Bake"}`)
+
+	payload := []byte(`{"text":"Bake until golden","duration":30}`)
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/1/steps/2", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest (PUT): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["text"], "Bake until golden", "Expected text to be 'Bake until golden'. Got '%v'", m["text"])
+	assert.Equalf(t, m["position"], 2.0, "Expected position to remain '2'. Got '%v'", m["position"])
+
+	req, err = http.NewRequest("PUT", "/v1/recipes/1/steps/9", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest (PUT): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestReorderAndDeleteSteps(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addStep(1, `{"text":"One"}`)
+	addStep(1, `{"text":"Two"}`)
+	addStep(1, `{"text":"Three"}`)
+
+	// Every step must be listed exactly once
+	for _, payload := range []string{`{"step_ids":[3,1]}`, `{"step_ids":[3,1,1]}`, `{"step_ids":[3,1,9]}`} {
+		req, err := http.NewRequest("POST", "/v1/recipes/1/steps/reorder", bytes.NewBufferString(payload))
+		assert.Nilf(t, err, "Error on http.NewRequest (reorder): %s", err)
+		req.SetBasicAuth(authUser, authPassword)
+		response := executeRequest(req)
+
+		checkResponseCode(t, http.StatusBadRequest, response.Code)
+	}
+
+	payload := []byte(`{"step_ids":[3,1,2]}`)
+
+	req, err := http.NewRequest("POST", "/v1/recipes/1/steps/reorder", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest (reorder): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+	assert.Equalf(t, []string{"Three", "One", "Two"}, stepTexts(response.Body.Bytes()), "Expected the steps to be reordered")
+
+	req, err = http.NewRequest("DELETE", "/v1/recipes/1/steps/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (GET): %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m struct {
+		Steps []map[string]interface{} `json:"steps"`
+	}
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	assert.Equalf(t, len(m.Steps), 2, "Expected '2' steps. Got '%v'", len(m.Steps))
+	for n, st := range m.Steps {
+		assert.Equalf(t, st["position"], float64(n+1), "Expected step positions to be renumbered. Got '%v'", st["position"])
+	}
+	assert.Equalf(t, m.Steps[1]["text"], "Two", "Expected second step to be 'Two'. Got '%v'", m.Steps[1]["text"])
+}
+
+func TestAddStepsConcurrently(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			addStep(1, `{"text":"Step `+strconv.Itoa(i)+`"}`)
+		}(i)
+	}
+	wg.Wait()
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1/steps", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	var steps []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &steps)
+	assert.Equalf(t, len(steps), 10, "Expected '10' steps. Got '%v'", len(steps))
+	for n, st := range steps {
+		assert.Equalf(t, st["position"], float64(n+1), "Expected each step to have its own position. Got '%v'", st["position"])
+	}
+}
+
+func TestGetStepsNonExistentRecipe(t *testing.T) {
+	clearTables()
+
+	req, err := http.NewRequest("GET", "/v1/recipes/11/steps", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func addStep(recipe int, payload string) {
+	req, _ := http.NewRequest("POST", "/v1/recipes/"+strconv.Itoa(recipe)+"/steps", bytes.NewBufferString(payload))
+	req.SetBasicAuth(authUser, authPassword)
+	executeRequest(req)
+}
+
+func stepTexts(body []byte) []string {
+	var steps []map[string]interface{}
+	json.Unmarshal(body, &steps)
+	texts := []string{}
+	for _, st := range steps {
+		texts = append(texts, st["text"].(string))
+	}
+	return texts
+}
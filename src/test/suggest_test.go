@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestSuggestRecipes(t *testing.T) {
+	clearTables()
+	addSearchableRecipes()
+
+	for prefix, expected := range map[string][]string{
+		"tom":      {"Tomato Soup"},
+		"TOMATO s": {"Tomato Soup"},
+		"tomoto":   {"Tomato Soup"},    // a substitution
+		"choclate": {"Chocolate Cake"}, // an omission
+		"pomodoro": {"Pasta al Pomodoro"},
+		"soup":     {"Tomato Soup"},
+		"xyz":      {},
+	} {
+		mm := suggestRecipes(t, prefix, "")
+		names := []string{}
+		for _, m := range mm {
+			names = append(names, m["name"].(string))
+		}
+		assert.Equalf(t, names, expected, "Expected suggestions '%v' for '%s'. Got '%v'", expected, prefix, names)
+	}
+
+	// exact prefixes are suggested before misspelt ones
+	mm := suggestRecipes(t, "pa", "")
+	if assert.Equalf(t, len(mm), 1, "Expected 1 suggestion. Got '%v'", len(mm)) {
+		assert.Equalf(t, mm[0]["id"], 2.0, "Expected recipe ID '2'. Got '%v'", mm[0]["id"])
+		assert.Equalf(t, mm[0]["score"], 1.0, "Expected a score of '1'. Got '%v'", mm[0]["score"])
+	}
+	mm = suggestRecipes(t, "cake", "")
+	if assert.Equalf(t, len(mm), 1, "Expected 1 suggestion. Got '%v'", len(mm)) {
+		assert.Equalf(t, mm[0]["name"], "Chocolate Cake", "Expected 'Chocolate Cake'. Got '%v'", mm[0]["name"])
+	}
+}
+
+func TestSuggestRecipesRanking(t *testing.T) {
+	clearTables()
+	addRecipes(3)
+
+	mm := suggestRecipes(t, "recipe 1", "")
+	names := []string{}
+	for _, m := range mm {
+		names = append(names, m["name"].(string))
+	}
+	// one substitution away from the others
+	expected := []string{"Recipe 1", "Recipe 0", "Recipe 2"}
+	assert.Equalf(t, names, expected, "Expected suggestions '%v'. Got '%v'", expected, names)
+
+	mm = suggestRecipes(t, "recipe 1", "2")
+	assert.Equalf(t, len(mm), 2, "Expected 2 suggestions. Got '%v'", len(mm))
+}
+
+func TestSuggestRecipesWithInvalidParams(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	for _, query := range []string{"", "prefix=", "prefix=%20", "prefix=tom&limit=0", "prefix=tom&limit=21", "prefix=tom&limit=x"} {
+		req, err := http.NewRequest("GET", "/v1/recipes/suggest?"+query, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		assert.Equalf(t, response.Code, http.StatusBadRequest, "Expected response code %d for '%s'. Got %d", http.StatusBadRequest, query, response.Code)
+	}
+
+	// suggest is never taken for a recipe ID
+	req, err := http.NewRequest("GET", "/v1/recipes/suggest?q=tomato", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+	assert.Containsf(t, response.Body.String(), "Invalid prefix", "Expected the prefix to be missing. Got '%s'", response.Body.String())
+}
+
+func suggestRecipes(t *testing.T, prefix string, limit string) []map[string]interface{} {
+	values := url.Values{"prefix": {prefix}}
+	if limit != "" {
+		values.Set("limit", limit)
+	}
+	req, err := http.NewRequest("GET", "/v1/recipes/suggest?"+values.Encode(), nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	return mm
+}
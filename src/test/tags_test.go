@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestSetRecipeTags(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	payload := []byte(`{"tags":["Quick ", "one  pot", "quick"]}`)
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/1/tags", bytes.NewBuffer(payload))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var tags []string
+	json.Unmarshal(response.Body.Bytes(), &tags)
+	// tags are normalized, de-duplicated and sorted
+	assert.Equalf(t, tags, []string{"one pot", "quick"}, "Expected tags 'one pot' and 'quick'. Got '%v'", tags)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["tags"], []interface{}{"one pot", "quick"}, "Expected the recipe to be tagged. Got '%v'", m["tags"])
+
+	// replacing the tags removes those not listed
+	setRecipeTags(1, `{"tags":["weeknight"]}`)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1/tags", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	json.Unmarshal(response.Body.Bytes(), &tags)
+	assert.Equalf(t, tags, []string{"weeknight"}, "Expected tag 'weeknight'. Got '%v'", tags)
+}
+
+func TestSetRecipeTagsNoCredentials(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/1/tags", bytes.NewBufferString(`{"tags":["quick"]}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestSetRecipeTagsWithInvalidTag(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	for payload, field := range map[string]string{
+		`{"tags":["quick", "  "]}`:            "tags",
+		`{"tags":["quick"],"cuisines":["x"]}`: "cuisines",
+	} {
+		req, err := http.NewRequest("PUT", "/v1/recipes/1/tags", bytes.NewBufferString(payload))
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		req.SetBasicAuth(authUser, authPassword)
+		response := executeRequest(req)
+
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+}
+
+func TestSetTagsOfNonExistentRecipe(t *testing.T) {
+	clearTables()
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/11/tags", bytes.NewBufferString(`{"tags":["quick"]}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+}
+
+func TestGetTags(t *testing.T) {
+	clearTables()
+	addRecipes(3)
+	setRecipeTags(1, `{"tags":["quick", "spicy"]}`)
+	setRecipeTags(2, `{"tags":["quick"]}`)
+	setRecipeTags(3, `{"tags":["budget", "quick"]}`)
+
+	req, err := http.NewRequest("GET", "/v1/tags", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	assert.Equalf(t, len(mm), 3, "Expected 3 tags. Got '%v'", len(mm))
+	assert.Equalf(t, mm[0]["name"], "quick", "Expected the most used tag first. Got '%v'", mm[0]["name"])
+	assert.Equalf(t, mm[0]["recipes"], 3.0, "Expected tag 'quick' to be used by '3' recipes. Got '%v'", mm[0]["recipes"])
+	assert.Equalf(t, mm[1]["name"], "budget", "Expected tag 'budget' second. Got '%v'", mm[1]["name"])
+}
+
+func TestGetCuisines(t *testing.T) {
+	clearTables()
+
+	req, err := http.NewRequest("GET", "/v1/cuisines", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	names := []interface{}{}
+	for _, m := range mm {
+		names = append(names, m["name"])
+		assert.Equalf(t, m["taxonomy"], "cuisine", "Expected taxonomy 'cuisine'. Got '%v'", m["taxonomy"])
+	}
+	assert.Containsf(t, names, "italian", "Expected cuisine 'italian'. Got '%v'", names)
+	assert.NotContainsf(t, names, "dessert", "Expected course 'dessert' not to be listed. Got '%v'", names)
+}
+
+func TestAddAndDeleteCourse(t *testing.T) {
+	clearTables()
+
+	req, err := http.NewRequest("POST", "/v1/courses", bytes.NewBufferString(`{"name":"Amuse-Bouche"}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+
+	req, err = http.NewRequest("POST", "/v1/courses", bytes.NewBufferString(`{"name":"Amuse-Bouche"}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusCreated, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["name"], "amuse-bouche", "Expected name to be 'amuse-bouche'. Got '%v'", m["name"])
+	assert.Equalf(t, m["taxonomy"], "course", "Expected taxonomy to be 'course'. Got '%v'", m["taxonomy"])
+	termID := strconv.Itoa(int(m["term_id"].(float64)))
+
+	req, err = http.NewRequest("POST", "/v1/courses", bytes.NewBufferString(`{"name":"amuse-bouche"}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusConflict, response.Code)
+
+	req, err = http.NewRequest("POST", "/v1/courses", bytes.NewBufferString(`{"name":"  "}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+	checkFieldError(t, p, "name")
+
+	// a course cannot be deleted as a cuisine
+	req, err = http.NewRequest("DELETE", "/v1/cuisines/"+termID, nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	req, err = http.NewRequest("DELETE", "/v1/courses/"+termID, nil)
+	assert.Nilf(t, err, "Error on http.NewRequest (DELETE): %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func TestSetRecipeCuisines(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("PUT", "/v1/recipes/1/cuisines", bytes.NewBufferString(`{"cuisines":["Thai","indian"]}`))
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.SetBasicAuth(authUser, authPassword)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	setRecipeTerms(1, "courses", `{"courses":["main"]}`)
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response = executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["cuisines"], []interface{}{"indian", "thai"}, "Expected cuisines 'indian' and 'thai'. Got '%v'", m["cuisines"])
+	assert.Equalf(t, m["courses"], []interface{}{"main"}, "Expected course 'main'. Got '%v'", m["courses"])
+
+	// only curated terms may be used
+	for payload, field := range map[string]string{
+		`{"cuisines":["martian"]}`:                 "cuisines",
+		`{"cuisines":["thai"],"courses":["main"]}`: "courses",
+	} {
+		req, err = http.NewRequest("PUT", "/v1/recipes/1/cuisines", bytes.NewBufferString(payload))
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		req.SetBasicAuth(authUser, authPassword)
+		response = executeRequest(req)
+
+		p := checkProblem(t, response, http.StatusUnprocessableEntity, "/problems/validation")
+		checkFieldError(t, p, field)
+	}
+
+	req, err = http.NewRequest("GET", "/v1/recipes/1/cuisines", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response = executeRequest(req)
+
+	var cuisines []string
+	json.Unmarshal(response.Body.Bytes(), &cuisines)
+	assert.Equalf(t, cuisines, []string{"indian", "thai"}, "Expected the cuisines to be unchanged. Got '%v'", cuisines)
+}
+
+func TestGetRecipesFilteredByTagAndCuisine(t *testing.T) {
+	clearTables()
+	addRecipes(4)
+	setRecipeTags(1, `{"tags":["quick"]}`)
+	setRecipeTags(2, `{"tags":["quick", "spicy"]}`)
+	setRecipeTags(3, `{"tags":["spicy"]}`)
+	setRecipeTerms(2, "cuisines", `{"cuisines":["thai"]}`)
+	setRecipeTerms(3, "cuisines", `{"cuisines":["thai"]}`)
+
+	for query, expected := range map[string][]string{
+		"?tag=quick":                {"Recipe 0", "Recipe 1"},
+		"?tag=Quick&tag=spicy":      {"Recipe 1"},
+		"?cuisine=thai":             {"Recipe 1", "Recipe 2"},
+		"?cuisine=thai&tag=quick":   {"Recipe 1"},
+		"?cuisine=thai&course=main": {},
+		"?tag=unknown":              {},
+	} {
+		req, err := http.NewRequest("GET", "/v1/recipes"+query, nil)
+		assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+		response := executeRequest(req)
+
+		checkResponseCode(t, http.StatusOK, response.Code)
+
+		var mm []map[string]interface{}
+		json.Unmarshal(response.Body.Bytes(), &mm)
+		names := []string{}
+		for _, m := range mm {
+			names = append(names, m["name"].(string))
+		}
+		assert.Equalf(t, names, expected, "Expected recipes '%v' for '%s'. Got '%v'", expected, query, names)
+	}
+}
+
+func TestSearchFilteredByTag(t *testing.T) {
+	clearTables()
+	addRecipes(3)
+	setRecipeTags(2, `{"tags":["quick"]}`)
+	setRecipeTags(3, `{"tags":["quick"]}`)
+
+	var bb bytes.Buffer
+	mw := multipart.NewWriter(&bb)
+	mw.WriteField("preptime", "25.0")
+	mw.WriteField("tag", "quick")
+	mw.Close()
+
+	req, err := http.NewRequest("POST", "/v1/search/recipes", &bb)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var mm []map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &mm)
+	assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm))
+	if len(mm) == 1 {
+		assert.Equalf(t, mm[0]["name"], "Recipe 1", "Expected 'Recipe 1'. Got '%v'", mm[0]["name"])
+	}
+}
+
+func setRecipeTags(recipe int, payload string) {
+	setRecipeTerms(recipe, "tags", payload)
+}
+
+func setRecipeTerms(recipe int, collection string, payload string) {
+	req, _ := http.NewRequest("PUT", "/v1/recipes/"+strconv.Itoa(recipe)+"/"+collection, bytes.NewBufferString(payload))
+	req.SetBasicAuth(authUser, authPassword)
+	executeRequest(req)
+}
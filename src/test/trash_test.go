@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+	// local import
+	"application"
+)
+
+func TestDeleteRecipeMovesItToTrash(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+	addIngredient(1, `{"quantity":2,"unit":"cup","item":"flour"}`)
+
+	response := conditionalRequest("DELETE", "/v1/recipes/1", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// deleted recipes are not found, listed or searched for
+	req, _ := http.NewRequest("GET", "/v1/recipes/1", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	m, _ := getRecipesPage(t, "GET", "/v1/recipes?limit=10")
+	assert.Equalf(t, m["total"], 1.0, "Expected a total of '1'. Got '%v'", m["total"])
+	mm := searchRecipes(t, map[string]string{})
+	assert.Equalf(t, len(mm), 1, "Expected 1 recipe. Got '%v'", len(mm))
+
+	// nor can they be deleted again
+	response = conditionalRequest("DELETE", "/v1/recipes/1", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+
+	trash := getTrash(t)
+	assert.Equalf(t, len(trash), 1, "Expected 1 recipe in the trash. Got '%v'", len(trash))
+	deleted := trash[0].(map[string]interface{})
+	assert.Equalf(t, deleted["name"], "Recipe 0", "Expected name 'Recipe 0'. Got '%v'", deleted["name"])
+	assert.NotNilf(t, deleted["deleted_at"], "Expected a 'deleted_at'. Got '%v'", deleted["deleted_at"])
+
+	req, _ = http.NewRequest("GET", "/v1/trash", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusUnauthorized, response.Code)
+}
+
+func TestRestoreRecipeFromTrash(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addIngredient(1, `{"quantity":2,"unit":"cup","item":"flour"}`)
+	conditionalRequest("DELETE", "/v1/recipes/1", "", "", "")
+
+	response := conditionalRequest("POST", "/v1/recipes/1/restore", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	var m map[string]interface{}
+	json.Unmarshal(response.Body.Bytes(), &m)
+	assert.Equalf(t, m["name"], "Recipe 0", "Expected name 'Recipe 0'. Got '%v'", m["name"])
+	assert.NotContainsf(t, m, "deleted_at", "Expected no 'deleted_at'. Got '%v'", m["deleted_at"])
+
+	// everything belonging to the recipe is restored along with it
+	req, _ := http.NewRequest("GET", "/v1/recipes/1/ingredients/1", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+	trash := getTrash(t)
+	assert.Equalf(t, len(trash), 0, "Expected an empty trash. Got '%v'", len(trash))
+
+	for _, path := range []string{"/v1/recipes/1/restore", "/v1/recipes/2/restore"} {
+		response = conditionalRequest("POST", path, "", "", "")
+		checkResponseCode(t, http.StatusNotFound, response.Code)
+	}
+}
+
+func TestRestoredRecipeIsANewVersion(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	req, _ := http.NewRequest("GET", "/v1/recipes/1", nil)
+	etag := executeRequest(req).Header().Get("ETag")
+	response := conditionalRequest("DELETE", "/v1/recipes/1", "If-Match", etag, "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	response = conditionalRequest("POST", "/v1/recipes/1/restore", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+	restored := response.Header().Get("ETag")
+	assert.NotEqualf(t, restored, etag, "Expected a new ETag after restore. Got '%v'", restored)
+
+	// so the version which was deleted may no longer be modified
+	payload := `{"name":"Recipe 0a","preptime":10,"difficulty":1,"vegetarian":true}`
+	response = conditionalRequest("PUT", "/v1/recipes/1", "If-Match", etag, payload)
+	checkProblem(t, response, http.StatusPreconditionFailed, "/problems/modified")
+	response = conditionalRequest("PATCH", "/v1/recipes/1", "If-Match", etag, `{"servings":4}`)
+	checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+	response = conditionalRequest("DELETE", "/v1/recipes/1", "If-Match", etag, "")
+	checkResponseCode(t, http.StatusPreconditionFailed, response.Code)
+	response = conditionalRequest("PUT", "/v1/recipes/1", "If-Match", restored, payload)
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	// the restore is recorded as a revision (which changes nothing)
+	revisions := getRevisions(t, "/v1/recipes/1/revisions")
+	assert.Equalf(t, len(revisions), 3, "Expected 3 revisions. Got '%v'", len(revisions))
+	restore := revisions[1].(map[string]interface{})
+	assert.Equalf(t, restore["author"], authUser, "Expected author '%v'. Got '%v'", authUser, restore["author"])
+	assert.Equalf(t, restore["changes"], map[string]interface{}{}, "Expected no changes. Got '%v'", restore["changes"])
+}
+
+func TestPurgeTrash(t *testing.T) {
+	clearTables()
+	addRecipes(2)
+	conditionalRequest("DELETE", "/v1/recipes/1", "", "", "")
+
+	// only recipes deleted before the retention period are purged
+	var out bytes.Buffer
+	err := application.Purge(&out, app.Store, time.Hour)
+	assert.Nilf(t, err, "Error on Purge: %s", err)
+	assert.Equalf(t, len(getTrash(t)), 1, "Expected 1 recipe in the trash. Got '%v'", len(getTrash(t)))
+
+	purged, err := app.Store.PurgeRecipes(time.Now().Add(time.Minute))
+	assert.Nilf(t, err, "Error on PurgeRecipes: %s", err)
+	assert.Equalf(t, purged, 1, "Expected 1 recipe purged. Got '%v'", purged)
+	assert.Equalf(t, len(getTrash(t)), 0, "Expected an empty trash. Got '%v'", len(getTrash(t)))
+
+	response := conditionalRequest("POST", "/v1/recipes/1/restore", "", "", "")
+	checkResponseCode(t, http.StatusNotFound, response.Code)
+	req, _ := http.NewRequest("GET", "/v1/recipes/2", nil)
+	response = executeRequest(req)
+	checkResponseCode(t, http.StatusOK, response.Code)
+}
+
+func TestParseRetention(t *testing.T) {
+	for period, expected := range map[string]time.Duration{
+		"":    application.DefaultTrashRetention,
+		"7d":  7 * 24 * time.Hour,
+		"36h": 36 * time.Hour,
+	} {
+		retention, err := application.ParseRetention(period)
+		assert.Nilf(t, err, "Error on ParseRetention(%q): %s", period, err)
+		assert.Equalf(t, retention, expected, "Expected '%v'. Got '%v'", expected, retention)
+	}
+	for _, period := range []string{"a week", "-1d", "-5h"} {
+		_, err := application.ParseRetention(period)
+		assert.NotNilf(t, err, "Expected an error on ParseRetention(%q)", period)
+	}
+}
+
+func getTrash(t *testing.T) []interface{} {
+	response := conditionalRequest("GET", "/v1/trash", "", "", "")
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var trash []interface{}
+	json.Unmarshal(response.Body.Bytes(), &trash)
+	return trash
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+type convertedRecipe struct {
+	Ingredients []struct {
+		Quantity float64 `json:"quantity"`
+		Unit     string  `json:"unit"`
+		Item     string  `json:"item"`
+	} `json:"ingredients"`
+	Steps []struct {
+		Text string `json:"text"`
+	} `json:"steps"`
+}
+
+func TestGetRecipeMetric(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addIngredient(1, `{"quantity":2,"unit":"cups","item":"all-purpose flour"}`)
+	addIngredient(1, `{"quantity":1,"unit":"cup","item":"milk"}`)
+	addIngredient(1, `{"quantity":2,"unit":"lb","item":"potatoes"}`)
+	addIngredient(1, `{"quantity":3,"unit":"","item":"eggs"}`)
+	addIngredient(1, `{"quantity":100,"unit":"g","item":"butter"}`)
+	addStep(1, `{"text":"Bake at 350°F for 25 minutes"}`)
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1?units=metric", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m convertedRecipe
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	// flour has a density hint, so is weighed
+	assert.Equalf(t, m.Ingredients[0].Unit, "g", "Expected flour in 'g'. Got '%v'", m.Ingredients[0].Unit)
+	assert.Equalf(t, m.Ingredients[0].Quantity, 251.0, "Expected '251' g of flour. Got '%v'", m.Ingredients[0].Quantity)
+	// milk does not, so stays a volume
+	assert.Equalf(t, m.Ingredients[1].Unit, "ml", "Expected milk in 'ml'. Got '%v'", m.Ingredients[1].Unit)
+	assert.Equalf(t, m.Ingredients[1].Quantity, 237.0, "Expected '237' ml of milk. Got '%v'", m.Ingredients[1].Quantity)
+	assert.Equalf(t, m.Ingredients[2].Unit, "g", "Expected potatoes in 'g'. Got '%v'", m.Ingredients[2].Unit)
+	assert.Equalf(t, m.Ingredients[2].Quantity, 907.0, "Expected '907' g of potatoes. Got '%v'", m.Ingredients[2].Quantity)
+	// unitless and already metric ingredients are unchanged
+	assert.Equalf(t, m.Ingredients[3].Quantity, 3.0, "Expected '3' eggs. Got '%v'", m.Ingredients[3].Quantity)
+	assert.Equalf(t, m.Ingredients[4].Unit, "g", "Expected butter in 'g'. Got '%v'", m.Ingredients[4].Unit)
+
+	assert.Equalf(t, m.Steps[0].Text, "Bake at 175°C for 25 minutes", "Expected the temperature to be converted. Got '%v'", m.Steps[0].Text)
+}
+
+func TestGetRecipeImperial(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+	addIngredient(1, `{"quantity":200,"unit":"grams","item":"caster sugar"}`)
+	addIngredient(1, `{"quantity":5,"unit":"ml","item":"vanilla extract"}`)
+	addIngredient(1, `{"quantity":1.5,"unit":"kg","item":"beef"}`)
+	addStep(1, `{"text":"Heat the oven to 180C"}`)
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1?units=imperial", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusOK, response.Code)
+
+	var m convertedRecipe
+	json.Unmarshal(response.Body.Bytes(), &m)
+
+	// sugar has a density hint, so is measured in cups
+	assert.Equalf(t, m.Ingredients[0].Unit, "cup", "Expected sugar in 'cup'. Got '%v'", m.Ingredients[0].Unit)
+	assert.Equalf(t, m.Ingredients[0].Quantity, 1.0, "Expected '1' cup of sugar. Got '%v'", m.Ingredients[0].Quantity)
+	assert.Equalf(t, m.Ingredients[1].Unit, "tsp", "Expected vanilla in 'tsp'. Got '%v'", m.Ingredients[1].Unit)
+	assert.Equalf(t, m.Ingredients[1].Quantity, 1.0, "Expected '1' tsp of vanilla. Got '%v'", m.Ingredients[1].Quantity)
+	assert.Equalf(t, m.Ingredients[2].Unit, "lb", "Expected beef in 'lb'. Got '%v'", m.Ingredients[2].Unit)
+	assert.Equalf(t, m.Ingredients[2].Quantity, 3.25, "Expected '3.25' lb of beef. Got '%v'", m.Ingredients[2].Quantity)
+
+	assert.Equalf(t, m.Steps[0].Text, "Heat the oven to 355°F", "Expected the temperature to be converted. Got '%v'", m.Steps[0].Text)
+}
+
+func TestGetRecipeWithInvalidUnits(t *testing.T) {
+	clearTables()
+	addRecipes(1)
+
+	req, err := http.NewRequest("GET", "/v1/recipes/1?units=furlongs", nil)
+	assert.Nilf(t, err, "Error on http.NewRequest: %s", err)
+	response := executeRequest(req)
+
+	checkResponseCode(t, http.StatusBadRequest, response.Code)
+}